@@ -0,0 +1,81 @@
+package lpoll
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddClientAlias makes aliasId another name for the registered client
+// canonicalId: publishes to the alias reach the canonical client, and polls
+// for it are redirected. An alias of an alias points at its canonical ID.
+func (s *Server) AddClientAlias(aliasId, canonicalId string) error {
+	canonicalId = s.resolveAlias(canonicalId)
+	if aliasId == canonicalId {
+		return fmt.Errorf("lpoll: client %s cannot be an alias of itself", aliasId)
+	}
+	if !s.ClientExists(canonicalId) {
+		return ErrClientNotFound
+	}
+	if s.ClientExists(aliasId) {
+		return fmt.Errorf("lpoll: alias %s is a registered client", aliasId)
+	}
+
+	s.aliasesMu.Lock()
+	defer s.aliasesMu.Unlock()
+	if s.aliases == nil {
+		s.aliases = make(map[string]string)
+	}
+	s.aliases[aliasId] = canonicalId
+	return nil
+}
+
+// RemoveClientAlias removes aliasId, if it is an alias.
+func (s *Server) RemoveClientAlias(aliasId string) {
+	s.aliasesMu.Lock()
+	defer s.aliasesMu.Unlock()
+	delete(s.aliases, aliasId)
+}
+
+// resolveAlias returns the canonical ID for clientId, or clientId itself.
+func (s *Server) resolveAlias(clientId string) string {
+	s.aliasesMu.RLock()
+	defer s.aliasesMu.RUnlock()
+	if canonicalId, ok := s.aliases[clientId]; ok {
+		return canonicalId
+	}
+	return clientId
+}
+
+// redirectToCanonical answers a poll for an alias with 301 to the poll URL
+// of canonicalId, built for the configured ClientIDSource. Header IDs
+// cannot be redirected, so the canonical ID is also sent in that header.
+// The Location is relative to the request URL, whose path the Echo and chi
+// adapters rewrite, so it stays under the router's prefix.
+func redirectToCanonical(c *gin.Context, opts LpollOptions, canonicalId string) {
+	canonicalId = strings.TrimPrefix(canonicalId, c.GetString(namespaceKey))
+	// Only the last path segment is replaced.
+	loc := url.URL{Path: path.Base(c.Request.URL.Path), RawQuery: c.Request.URL.RawQuery}
+	switch opts.ClientIDSource {
+	case QueryParam:
+		q := c.Request.URL.Query()
+		q.Set("clientId", canonicalId)
+		loc.RawQuery = q.Encode()
+	case Header:
+		name := opts.ClientIDHeaderName
+		if name == "" {
+			name = DefaultClientIDHeaderName
+		}
+		c.Header(name, canonicalId)
+	default:
+		loc.Path = canonicalId
+	}
+	// Not c.Redirect: http.Redirect would resolve the Location against the
+	// rewritten path.
+	c.Header("Location", loc.String())
+	c.Status(http.StatusMovedPermanently)
+}
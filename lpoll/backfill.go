@@ -0,0 +1,30 @@
+package lpoll
+
+import "fmt"
+
+// Backfill registers clientId if needed and enqueues events in order, ahead
+// of live traffic. Events without a Source are marked "backfill". Backfilled
+// events bypass the publish hooks and the event store. If the channel fills
+// up, it returns an error wrapping ErrBufferFull with the index of the first
+// event that was not enqueued.
+func (s *Server) Backfill(clientId string, events []Event) error {
+	s.EnsureClient(clientId)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return ErrClientNotFound
+	}
+	for i, event := range events {
+		if event.Source == "" {
+			event.Source = "backfill"
+		}
+		select {
+		case client.Channel <- s.compressEvent(event):
+		default:
+			return fmt.Errorf("%w: first unsent event at index %d", ErrBufferFull, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package lpoll
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BenchmarkResult summarizes a Benchmark run.
+type BenchmarkResult struct {
+	EventsPublished uint64  `json:"eventsPublished"`
+	EventsDelivered uint64  `json:"eventsDelivered"`
+	EventsDropped   uint64  `json:"eventsDropped"`
+	MeanLatencyMs   float64 `json:"meanLatencyMs"`
+	P99LatencyMs    float64 `json:"p99LatencyMs"`
+	ThroughputEPS   float64 `json:"throughputEps"`
+}
+
+// Benchmark registers the given number of temporary clients, each drained by
+// its own consumer, and runs publishers goroutines against them for duration.
+// It sends on the channels directly, so neither HTTP overhead nor the
+// publish hooks, middleware, event store, OnPublishSuccess, taps or server
+// stats are involved. The temporary clients are removed before it returns. duration is capped at
+// half the client timeout so the cleanup never evicts a client mid-run.
+func (s *Server) Benchmark(clients, publishers int, duration time.Duration) BenchmarkResult {
+	var result BenchmarkResult
+	if clients <= 0 || publishers <= 0 || duration <= 0 {
+		return result
+	}
+	if _, clientTimeout := s.timeouts(); duration > clientTimeout/2 {
+		duration = clientTimeout / 2
+	}
+
+	prefix := fmt.Sprintf("lpoll-benchmark-%d-", time.Now().UnixNano())
+	ids := make([]string, clients)
+	states := make([]*ClientState, clients)
+	s.mu.Lock()
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s%d", prefix, i)
+		now := time.Now()
+		states[i] = &ClientState{Channel: make(chan Event, 1), LastSeen: now, RegisteredAt: now}
+		s.clientChannels[ids[i]] = states[i]
+	}
+	s.mu.Unlock()
+
+	var delivered atomic.Uint64
+	latencies := make([][]time.Duration, clients)
+	var consumers sync.WaitGroup
+	for i, state := range states {
+		consumers.Add(1)
+		go func(i int, ch chan Event) {
+			defer consumers.Done()
+			for event := range ch {
+				latencies[i] = append(latencies[i], time.Since(event.Time))
+				delivered.Add(1)
+			}
+		}(i, state.Channel)
+	}
+
+	// send is a raw non-blocking channel send, like SelfTest's, so the
+	// benchmark is invisible to OnPublishSuccess, the taps and the stats.
+	send := func(i int, event Event) bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		// A drain, restore or cleanup may have evicted the client.
+		if s.clientChannels[ids[i]] != states[i] {
+			return false
+		}
+		select {
+		case states[i].Channel <- event:
+			return true
+		default:
+			return false
+		}
+	}
+
+	var published, dropped atomic.Uint64
+	var producers sync.WaitGroup
+	start := time.Now()
+	deadline := start.Add(duration)
+	for p := 0; p < publishers; p++ {
+		producers.Add(1)
+		go func(p int) {
+			defer producers.Done()
+			for n := p; time.Now().Before(deadline); n++ {
+				if !send(n%clients, Event{Message: "benchmark", Time: time.Now()}) {
+					dropped.Add(1)
+					continue
+				}
+				published.Add(1)
+			}
+		}(p)
+	}
+	producers.Wait()
+	elapsed := time.Since(start)
+
+	s.mu.Lock()
+	for i, id := range ids {
+		// A drain, restore or cleanup may have evicted the client already.
+		if s.clientChannels[id] != states[i] {
+			continue
+		}
+		delete(s.clientChannels, id)
+		closeChannel(states[i])
+	}
+	s.mu.Unlock()
+	consumers.Wait()
+
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	result.EventsPublished = published.Load()
+	result.EventsDelivered = delivered.Load()
+	result.EventsDropped = dropped.Load()
+	result.ThroughputEPS = float64(result.EventsDelivered) / elapsed.Seconds()
+	if len(all) > 0 {
+		sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+		var total time.Duration
+		for _, d := range all {
+			total += d
+		}
+		result.MeanLatencyMs = float64(total) / float64(len(all)) / float64(time.Millisecond)
+		result.P99LatencyMs = float64(all[(len(all)-1)*99/100]) / float64(time.Millisecond)
+	}
+	return result
+}
+
+// BenchmarkHandler runs Benchmark with the parameters in the request body,
+// e.g. {"clients": 100, "publishers": 4, "duration": "10s"}.
+func BenchmarkHandler(c *gin.Context) {
+	var req struct {
+		Clients    int    `json:"clients" binding:"required,min=1"`
+		Publishers int    `json:"publishers" binding:"required,min=1"`
+		Duration   string `json:"duration" binding:"required"`
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be a positive duration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, serverFrom(c).Benchmark(req.Clients, req.Publishers, duration))
+}
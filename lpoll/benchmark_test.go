@@ -0,0 +1,82 @@
+package lpoll
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingHook counts the events that reach the publish hooks.
+type countingHook struct{ published chan struct{} }
+
+func (h countingHook) OnPublish(ctx context.Context, clientId string, event *Event) error {
+	select {
+	case h.published <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (h countingHook) OnDeliver(ctx context.Context, clientId string, event Event) {}
+
+func TestBenchmarkSkipsPublishHooks(t *testing.T) {
+	s := New(LpollOptions{})
+	hook := countingHook{published: make(chan struct{}, 1)}
+	s.AddEventHook(hook)
+
+	result := s.Benchmark(2, 1, 50*time.Millisecond)
+	if result.EventsPublished == 0 {
+		t.Fatal("no events published")
+	}
+	select {
+	case <-hook.published:
+		t.Fatal("benchmark events reached the publish hooks")
+	default:
+	}
+	if n := len(s.clientChannels); n != 0 {
+		t.Fatalf("%d benchmark clients left", n)
+	}
+}
+
+func TestBenchmarkDuringDrain(t *testing.T) {
+	s := New(LpollOptions{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Benchmark(4, 2, 200*time.Millisecond)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	s.DrainAndClose()
+	<-done
+}
+
+func TestBenchmarkSkipsPublishCallbacks(t *testing.T) {
+	var mu sync.Mutex
+	var succeeded, tapped int
+	s := New(LpollOptions{
+		OnPublishSuccess: func(string, Event, int) {
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		},
+	})
+	unsubscribe := s.Subscribe("*", func(string, Event) {
+		mu.Lock()
+		tapped++
+		mu.Unlock()
+	})
+	defer unsubscribe()
+
+	if result := s.Benchmark(2, 1, 20*time.Millisecond); result.EventsPublished == 0 {
+		t.Fatal("no events published")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if succeeded != 0 || tapped != 0 {
+		t.Fatalf("benchmark events seen by %d OnPublishSuccess and %d tap calls", succeeded, tapped)
+	}
+	if n := s.stats.eventsPublished.Value() + s.stats.eventsDropped.Value(); n != 0 {
+		t.Fatalf("benchmark events counted in the server stats: %d", n)
+	}
+}
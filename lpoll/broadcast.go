@@ -0,0 +1,72 @@
+package lpoll
+
+import (
+	"log"
+	"path"
+	"sync/atomic"
+	"time"
+)
+
+// PublishBroadcastFiltered does a non-blocking send of event to every
+// client for which predicate returns true and returns the number of clients
+// it was delivered to. predicate runs under the server's read lock and must
+// not call back into s; a client whose predicate panics is skipped. The
+// publish hooks and the event store are not involved. Nothing is sent once
+// DrainAndClose has started.
+func (s *Server) PublishBroadcastFiltered(predicate func(string, *ClientState) bool, event Event) int {
+	delivered, _ := s.broadcast(predicate, event)
+	return delivered
+}
+
+// PublishToPattern does a non-blocking send of event to every client whose
+// ID matches pattern (path.Match glob syntax), like
+// PublishBroadcastFiltered. matched counts the matching clients and
+// dropped those whose channel was full.
+func (s *Server) PublishToPattern(pattern string, event Event) (matched int, dropped int) {
+	delivered, dropped := s.broadcast(func(clientId string, _ *ClientState) bool {
+		ok, _ := path.Match(pattern, clientId)
+		return ok
+	}, event)
+	return delivered + dropped, dropped
+}
+
+func (s *Server) broadcast(predicate func(string, *ClientState) bool, event Event) (delivered, dropped int) {
+	if s.draining.Load() {
+		return 0, 0
+	}
+	if event.PublishedAt.IsZero() {
+		event.PublishedAt = time.Now()
+	}
+
+	buffered := s.compressEvent(event)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for clientId, client := range s.clientChannels {
+		if !matchClient(predicate, clientId, client) {
+			continue
+		}
+		select {
+		case client.Channel <- buffered:
+			delivered++
+			atomic.AddUint64(&client.TotalPublished, 1)
+			s.stats.eventsPublished.Add(1)
+			s.notifyTaps(clientId, event)
+		default:
+			dropped++
+			s.stats.eventsDropped.Add(1)
+		}
+	}
+	return delivered, dropped
+}
+
+// matchClient calls predicate, treating a panic as no match.
+func matchClient(predicate func(string, *ClientState) bool, clientId string, client *ClientState) (matched bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Broadcast predicate panicked for client %s: %v", clientId, r)
+			matched = false
+		}
+	}()
+	return predicate(clientId, client)
+}
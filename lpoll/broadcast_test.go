@@ -0,0 +1,33 @@
+package lpoll
+
+import "testing"
+
+func TestPublishToPattern(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("room-1")
+	s.EnsureClient("room-2")
+	s.EnsureClient("other")
+
+	if matched, dropped := s.PublishToPattern("room-*", Event{Message: "hi"}); matched != 2 || dropped != 0 {
+		t.Fatalf("got %d matched, %d dropped, want 2 and 0", matched, dropped)
+	}
+	if n := len(s.clientChannels["other"].Channel); n != 0 {
+		t.Fatal("non-matching client received the event")
+	}
+}
+
+func TestBroadcastWhileDraining(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+	s.draining.Store(true)
+
+	if n := s.PublishBroadcastFiltered(func(string, *ClientState) bool { return true }, Event{Message: "hi"}); n != 0 {
+		t.Fatalf("delivered to %d clients while draining", n)
+	}
+	if matched, _ := s.PublishToPattern("*", Event{Message: "hi"}); matched != 0 {
+		t.Fatalf("matched %d clients while draining", matched)
+	}
+	if n := len(s.clientChannels["c1"].Channel); n != 0 {
+		t.Fatalf("%d events buffered while draining", n)
+	}
+}
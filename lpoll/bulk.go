@@ -0,0 +1,57 @@
+package lpoll
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// BulkPublishResult reports per client whether a BulkPublish event was
+// delivered or dropped.
+type BulkPublishResult struct {
+	Delivered map[string]bool
+	// Dropped holds the clients whose channel was full or that are not
+	// registered, or every client while the server is draining.
+	Dropped map[string]bool
+}
+
+// BulkPublish does a non-blocking send of each event to its client, all
+// under a single acquisition of the read lock. Like
+// PublishBroadcastFiltered it bypasses the publish hooks and the event
+// store. Once DrainAndClose has started every event is dropped.
+func (s *Server) BulkPublish(events map[string]Event) BulkPublishResult {
+	result := BulkPublishResult{
+		Delivered: make(map[string]bool),
+		Dropped:   make(map[string]bool),
+	}
+	if s.draining.Load() {
+		for clientId := range events {
+			result.Dropped[clientId] = true
+		}
+		return result
+	}
+	now := time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for clientId, event := range events {
+		if event.PublishedAt.IsZero() {
+			event.PublishedAt = now
+		}
+		client, ok := s.clientChannels[clientId]
+		if !ok {
+			result.Dropped[clientId] = true
+			continue
+		}
+		select {
+		case client.Channel <- s.compressEvent(event):
+			result.Delivered[clientId] = true
+			atomic.AddUint64(&client.TotalPublished, 1)
+			s.stats.eventsPublished.Add(1)
+			s.notifyTaps(clientId, event)
+		default:
+			result.Dropped[clientId] = true
+			s.stats.eventsDropped.Add(1)
+		}
+	}
+	return result
+}
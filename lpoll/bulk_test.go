@@ -0,0 +1,27 @@
+package lpoll
+
+import "testing"
+
+func TestBulkPublish(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+
+	result := s.BulkPublish(map[string]Event{"c1": {Message: "hi"}, "missing": {Message: "hi"}})
+	if !result.Delivered["c1"] || !result.Dropped["missing"] {
+		t.Fatalf("got %+v, want c1 delivered and missing dropped", result)
+	}
+}
+
+func TestBulkPublishWhileDraining(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+	s.draining.Store(true)
+
+	result := s.BulkPublish(map[string]Event{"c1": {Message: "hi"}})
+	if len(result.Delivered) != 0 || !result.Dropped["c1"] {
+		t.Fatalf("got %+v, want c1 dropped", result)
+	}
+	if n := len(s.clientChannels["c1"].Channel); n != 0 {
+		t.Fatalf("%d events buffered while draining", n)
+	}
+}
@@ -0,0 +1,60 @@
+package lpoll
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventETag identifies a delivered event. Without an EventStore there is no
+// Seq, so the publish time is used instead.
+func eventETag(event Event) string {
+	id := event.Seq
+	if id == 0 {
+		id = uint64(event.Time.UnixNano())
+	}
+	return `"` + strconv.FormatUint(id, 10) + `"`
+}
+
+func (s *Server) isCacheable(event Event) bool {
+	return event.Type != "" && slices.Contains(s.options().CacheableEventTypes, event.Type)
+}
+
+// notModified answers 304 when the request's If-None-Match names the last
+// cacheable event delivered to client and nothing newer is buffered.
+func (s *Server) notModified(c *gin.Context, client *ClientState) bool {
+	inm := c.GetHeader("If-None-Match")
+	if inm == "" {
+		return false
+	}
+
+	s.mu.RLock()
+	cached := client.lastCacheable
+	buffered := len(client.Channel)
+	s.mu.RUnlock()
+	if cached == nil || buffered > 0 || inm != eventETag(*cached) {
+		return false
+	}
+
+	c.Header("ETag", inm)
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// setCacheHeaders marks a cacheable event's response as cacheable and
+// remembers the event for later If-None-Match requests.
+func (s *Server) setCacheHeaders(c *gin.Context, client *ClientState, event Event) {
+	if !s.isCacheable(event) {
+		return
+	}
+
+	c.Header("Cache-Control", "max-age="+strconv.Itoa(int(s.options().CacheMaxAge.Seconds())))
+	c.Header("ETag", eventETag(event))
+	c.Header("Last-Modified", event.Time.UTC().Format(http.TimeFormat))
+
+	s.mu.Lock()
+	client.lastCacheable = &event
+	s.mu.Unlock()
+}
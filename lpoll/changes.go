@@ -0,0 +1,62 @@
+package lpoll
+
+import (
+	"context"
+	"time"
+)
+
+// ClientEventKind is the kind of lifecycle change a ClientEvent reports.
+type ClientEventKind string
+
+const (
+	ClientRegistered ClientEventKind = "registered"
+	ClientEvicted    ClientEventKind = "evicted"
+)
+
+// ClientEvent is a client lifecycle change.
+type ClientEvent struct {
+	Kind     ClientEventKind `json:"kind"`
+	ClientID string          `json:"clientId"`
+	At       time.Time       `json:"at"`
+}
+
+// changesBuffer is the capacity of each SubscribeChanges channel.
+const changesBuffer = 64
+
+// SubscribeChanges returns a channel receiving every client registration
+// and eviction until ctx is cancelled, when the channel is closed. Changes
+// are dropped for a subscriber whose channel is full.
+func (s *Server) SubscribeChanges(ctx context.Context) <-chan ClientEvent {
+	ch := make(chan ClientEvent, changesBuffer)
+
+	s.changesMu.Lock()
+	s.changeSubscribers = append(s.changeSubscribers, ch)
+	s.changesMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.changesMu.Lock()
+		defer s.changesMu.Unlock()
+		for i, other := range s.changeSubscribers {
+			if other == ch {
+				s.changeSubscribers = append(s.changeSubscribers[:i:i], s.changeSubscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// emitChange fans a lifecycle change out to the subscribers without blocking.
+func (s *Server) emitChange(kind ClientEventKind, clientId string) {
+	event := ClientEvent{Kind: kind, ClientID: clientId, At: time.Now()}
+	s.changesMu.RLock()
+	defer s.changesMu.RUnlock()
+	for _, ch := range s.changeSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
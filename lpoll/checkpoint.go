@@ -0,0 +1,188 @@
+package lpoll
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// checkpointMagic starts every Checkpoint, followed by checkpointVersion.
+var checkpointMagic = []byte("LPOLLCKP")
+
+const checkpointVersion byte = 1
+
+// checkpointClient is the gob form of one client.
+type checkpointClient struct {
+	ID             string
+	LastSeen       time.Time
+	RegisteredAt   time.Time
+	PushToken      string
+	TotalPublished uint64
+	DeliveredSeq   uint64
+	Metadata       map[string]string
+	// ThrottleRPS is zero for unthrottled clients.
+	ThrottleRPS float64
+	// Capacity is zero for restored clients without a channel.
+	Capacity int
+	Events   []Event
+}
+
+// Checkpoint writes the state of every client, including its buffered
+// events, to w in a binary format Restore reads. The buffered events are
+// taken out and put back under the client lock; blocking publishes that
+// complete meanwhile may end up ahead of them.
+func (s *Server) Checkpoint(w io.Writer) error {
+	s.mu.Lock()
+	clients := make([]checkpointClient, 0, len(s.clientChannels))
+	for clientId, client := range s.clientChannels {
+		cc := checkpointClient{
+			ID:             clientId,
+			LastSeen:       client.LastSeen,
+			RegisteredAt:   client.RegisteredAt,
+			PushToken:      client.PushToken,
+			TotalPublished: atomic.LoadUint64(&client.TotalPublished),
+			DeliveredSeq:   atomic.LoadUint64(&client.deliveredSeq),
+			Metadata:       client.metadata,
+			Capacity:       cap(client.Channel),
+		}
+		if client.limiter != nil {
+			cc.ThrottleRPS = float64(client.limiter.Limit())
+		}
+		cc.Events = peekBuffered(clientId, client)
+		clients = append(clients, cc)
+	}
+	// Encode under the lock, as the metadata maps are shared.
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(clients)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(append(checkpointMagic, checkpointVersion)); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// Restore replaces all clients with the ones in a Checkpoint read from r.
+// Clients that are not in the checkpoint are evicted. Nothing changes if r
+// cannot be read.
+func (s *Server) Restore(r io.Reader) error {
+	header := make([]byte, len(checkpointMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("lpoll: reading checkpoint header: %w", err)
+	}
+	if !bytes.Equal(header[:len(checkpointMagic)], checkpointMagic) {
+		return errors.New("lpoll: not a checkpoint")
+	}
+	if v := header[len(checkpointMagic)]; v > checkpointVersion {
+		return fmt.Errorf("lpoll: unsupported checkpoint version %d", v)
+	}
+
+	var clients []checkpointClient
+	if err := gob.NewDecoder(r).Decode(&clients); err != nil {
+		return fmt.Errorf("lpoll: decoding checkpoint: %w", err)
+	}
+
+	restored := make(map[string]*ClientState, len(clients))
+	for _, cc := range clients {
+		client := &ClientState{
+			LastSeen:       cc.LastSeen,
+			RegisteredAt:   cc.RegisteredAt,
+			PushToken:      cc.PushToken,
+			TotalPublished: cc.TotalPublished,
+			metadata:       cc.Metadata,
+			deliveredSeq:   cc.DeliveredSeq,
+		}
+		if cc.ThrottleRPS > 0 {
+			client.limiter = rate.NewLimiter(rate.Limit(cc.ThrottleRPS), 1)
+		}
+		if cc.Capacity > 0 {
+			size := cc.Capacity
+			if len(cc.Events) > size {
+				size = len(cc.Events)
+			}
+			client.Channel = make(chan Event, size)
+			s.resetStoreCursor(client)
+			for _, event := range cc.Events {
+				client.Channel <- s.compressEvent(event)
+				if event.Seq > client.storeSeq {
+					client.storeSeq = event.Seq
+				}
+			}
+		}
+		restored[cc.ID] = client
+	}
+
+	var evicted []string
+	s.mu.Lock()
+	for clientId, client := range s.clientChannels {
+		// Polls waiting on a replaced client's old channel end as if it
+		// was evicted; the restored state serves the next poll.
+		closeChannel(client)
+		if _, ok := restored[clientId]; !ok {
+			s.emitChange(ClientEvicted, clientId)
+			evicted = append(evicted, clientId)
+		}
+	}
+	s.clientChannels = restored
+	metas := make(map[string]ClientMeta, len(restored))
+	for clientId, client := range restored {
+		metas[clientId] = s.clientMeta(client)
+	}
+	s.mu.Unlock()
+
+	for _, clientId := range evicted {
+		s.deleteClientState(clientId)
+	}
+
+	for clientId, meta := range metas {
+		s.saveClientState(clientId, meta)
+	}
+	log.Printf("Restored %d clients from checkpoint", len(restored))
+	return nil
+}
+
+// peekBuffered returns a decompressed copy of the events buffered for
+// client by taking them out and putting them back. Call it under the write
+// lock, so no publish can take the freed room meanwhile.
+func peekBuffered(clientId string, client *ClientState) []Event {
+	if client.Channel == nil {
+		return nil
+	}
+	var events []Event
+	for _, event := range bufferedEvents(client.Channel) {
+		events = append(events, decompressEvent(event))
+		select {
+		case client.Channel <- event:
+		default:
+			log.Printf("Could not requeue an event for client %s", clientId)
+		}
+	}
+	return events
+}
+
+// bufferedEvents takes every event currently buffered in ch.
+func bufferedEvents(ch chan Event) []Event {
+	var events []Event
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package lpoll
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestCheckpointRestore(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+	if err := s.send("c1", Event{Message: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Checkpoint(&buf); err != nil {
+		t.Fatal(err)
+	}
+	restored := New(LpollOptions{})
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := restored.EventCount("c1"); !ok || n != 1 {
+		t.Fatalf("restored event count %d, %v, want 1", n, ok)
+	}
+	if event := <-restored.clientChannels["c1"].Channel; event.Message != "hi" {
+		t.Fatalf("restored event %q, want \"hi\"", event.Message)
+	}
+}
+
+// Run with -race: PublishWithContext counts its events outside the lock.
+func TestCheckpointDuringPublish(t *testing.T) {
+	s := New(LpollOptions{})
+	client := s.EnsureClient("c1")
+	if err := s.ResizeClientChannel("c1", 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	var publishers sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		publishers.Add(1)
+		go func() {
+			defer publishers.Done()
+			for i := 0; i < 100; i++ {
+				if err := s.PublishWithContext(context.Background(), "c1", Event{Message: "hi"}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		publishers.Wait()
+		close(done)
+	}()
+	for publishing := true; publishing; {
+		if err := s.Checkpoint(&bytes.Buffer{}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-done:
+			publishing = false
+		default:
+		}
+	}
+	if n := len(s.clientChannel(client)); n != 400 {
+		t.Fatalf("%d events buffered, want 400", n)
+	}
+}
@@ -0,0 +1,39 @@
+// Package chi mounts lpoll on a chi router.
+package chi
+
+import (
+	"net/http"
+
+	chiv5 "github.com/go-chi/chi/v5"
+	"github.com/syosifov/lpoll/lpoll"
+)
+
+// AttachChiHandler mounts the lpoll routes of s on r under prefix. Requests
+// are served by s.Handler with the path rewritten to the lpoll route and
+// the {clientId} URL parameter, if any; the query string is kept.
+func AttachChiHandler(s *lpoll.Server, r chiv5.Router, prefix string) {
+	h := s.Handler()
+	r.Route(prefix, func(r chiv5.Router) {
+		for _, route := range []struct{ method, path string }{
+			{http.MethodGet, "/poll"},
+			{http.MethodPost, "/publish"},
+			{http.MethodGet, "/ws"},
+		} {
+			r.Method(route.method, route.path, forward(h, route.path))
+			r.Method(route.method, route.path+"/{clientId}", forward(h, route.path))
+		}
+		r.Method(http.MethodGet, "/health", forward(h, "/health"))
+	})
+}
+
+func forward(h http.Handler, path string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := req.Clone(req.Context())
+		r.URL.Path = path
+		if clientId := chiv5.URLParam(req, "clientId"); clientId != "" {
+			r.URL.Path += "/" + clientId
+		}
+		r.URL.RawPath = ""
+		h.ServeHTTP(w, r)
+	})
+}
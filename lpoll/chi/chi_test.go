@@ -0,0 +1,120 @@
+package chi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	chiv5 "github.com/go-chi/chi/v5"
+	"github.com/syosifov/lpoll/lpoll"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestAttachChiHandler(t *testing.T) {
+	s := lpoll.New(lpoll.LpollOptions{})
+	r := chiv5.NewRouter()
+	AttachChiHandler(s, r, "/lp")
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/lp/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("health: got %d, want 200", resp.StatusCode)
+	}
+
+	type result struct {
+		event lpoll.Event
+		code  int
+		err   error
+	}
+	polled := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(ts.URL + "/lp/poll/c1")
+		if err != nil {
+			polled <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		var event lpoll.Event
+		err = json.NewDecoder(resp.Body).Decode(&event)
+		polled <- result{event, resp.StatusCode, err}
+	}()
+
+	for i := 0; !s.ClientExists("c1"); i++ {
+		if i == 100 {
+			t.Fatal("poll did not register the client")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	resp, err = http.Post(ts.URL+"/lp/publish/c1", "application/json", strings.NewReader(`{"message":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("publish: got %d, want 200", resp.StatusCode)
+	}
+
+	select {
+	case res := <-polled:
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if res.code != http.StatusOK || res.event.Message != "hi" {
+			t.Fatalf("poll: got %d %q, want 200 \"hi\"", res.code, res.event.Message)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("poll did not return the published event")
+	}
+
+	resp, err = http.Get(ts.URL + "/poll/c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unprefixed route: got %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestAttachChiHandlerAliasRedirect(t *testing.T) {
+	s := lpoll.New(lpoll.LpollOptions{})
+	s.EnsureClient("canon")
+	if err := s.AddClientAlias("al", "canon"); err != nil {
+		t.Fatal(err)
+	}
+	r := chiv5.NewRouter()
+	AttachChiHandler(s, r, "/lp")
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(ts.URL + "/lp/poll/al?after=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Fatalf("got %d, want 301", resp.StatusCode)
+	}
+	loc, err := resp.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.Path != "/lp/poll/canon" || loc.RawQuery != "after=1" {
+		t.Fatalf("redirected to %s, want /lp/poll/canon?after=1", loc)
+	}
+}
@@ -0,0 +1,38 @@
+package lpoll
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// clientCertTLSConfig returns the TLS config for RequireClientCert, or nil
+// when it is off.
+func clientCertTLSConfig(opts LpollOptions) (*tls.Config, error) {
+	if !opts.RequireClientCert {
+		return nil, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(opts.ClientCACert) {
+		return nil, errors.New("lpoll: ClientCACert contains no PEM certificates")
+	}
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}, nil
+}
+
+// checkClientCertCN checks that the common name of r's verified client
+// certificate is clientId. Unverified certificates, as accepted by
+// tls.RequestClientCert or tls.RequireAnyClientCert, are rejected.
+func checkClientCertCN(r *http.Request, clientId string) error {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return fmt.Errorf("%w: no verified client certificate", ErrClientCert)
+	}
+	if cn := r.TLS.VerifiedChains[0][0].Subject.CommonName; cn != clientId {
+		return fmt.Errorf("%w: common name is %q", ErrClientCert, cn)
+	}
+	return nil
+}
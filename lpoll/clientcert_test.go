@@ -0,0 +1,28 @@
+package lpoll
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckClientCertCN(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "victim"}}
+
+	r := httptest.NewRequest("GET", "/poll/victim", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if err := checkClientCertCN(r, "victim"); !errors.Is(err, ErrClientCert) {
+		t.Fatalf("unverified certificate: got %v, want ErrClientCert", err)
+	}
+
+	r.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	if err := checkClientCertCN(r, "victim"); err != nil {
+		t.Fatalf("verified certificate: %v", err)
+	}
+	if err := checkClientCertCN(r, "other"); !errors.Is(err, ErrClientCert) {
+		t.Fatalf("mismatched CN: got %v, want ErrClientCert", err)
+	}
+}
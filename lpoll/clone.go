@@ -0,0 +1,56 @@
+package lpoll
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// CloneClient registers dst with the configuration of src: its metadata,
+// throttle and channel capacity. The push token identifies src's device
+// and is not copied. dst starts with an empty channel and must not be
+// registered yet.
+func (s *Server) CloneClient(src, dst string) error {
+	now := time.Now()
+	s.mu.Lock()
+	from, ok := s.clientChannels[src]
+	if !ok {
+		s.mu.Unlock()
+		return ErrClientNotFound
+	}
+	if _, ok := s.clientChannels[dst]; ok {
+		s.mu.Unlock()
+		return fmt.Errorf("lpoll: client %s already exists", dst)
+	}
+
+	size := cap(from.Channel)
+	if size == 0 {
+		size = 1
+	}
+	client := &ClientState{
+		Channel:      make(chan Event, size),
+		LastSeen:     now,
+		RegisteredAt: now,
+		deliveredSeq: lastSeq.Load(),
+	}
+	s.resetStoreCursor(client)
+	if from.metadata != nil {
+		client.metadata = make(map[string]string, len(from.metadata))
+		for k, v := range from.metadata {
+			client.metadata[k] = v
+		}
+	}
+	if from.limiter != nil {
+		client.limiter = rate.NewLimiter(from.limiter.Limit(), from.limiter.Burst())
+	}
+	s.clientChannels[dst] = client
+	s.emitChange(ClientRegistered, dst)
+	meta := s.clientMeta(client)
+	s.mu.Unlock()
+
+	log.Printf("Client %s cloned from %s", dst, src)
+	s.saveClientState(dst, meta)
+	return nil
+}
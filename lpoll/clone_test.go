@@ -0,0 +1,35 @@
+package lpoll
+
+import "testing"
+
+func TestCloneClient(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("src")
+	if err := s.SetPushToken("src", "device-token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetClientMetadata("src", "plan", "pro"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CloneClient("src", "dst"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.RLock()
+	token := s.clientChannels["dst"].PushToken
+	s.mu.RUnlock()
+	if token != "" {
+		t.Fatalf("clone has push token %q", token)
+	}
+	if value, ok, err := s.GetClientMetadata("dst", "plan"); err != nil || !ok || value != "pro" {
+		t.Fatalf("clone metadata plan = %q, %v, %v", value, ok, err)
+	}
+
+	// The clone's metadata is its own.
+	if err := s.SetClientMetadata("dst", "plan", "free"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _, _ := s.GetClientMetadata("src", "plan"); value != "pro" {
+		t.Fatalf("source metadata plan = %q after changing the clone", value)
+	}
+}
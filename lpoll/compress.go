@@ -0,0 +1,47 @@
+package lpoll
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log"
+)
+
+// compressEvent gzips event.Message for buffering when
+// CompressBufferedEvents is set. Messages that do not shrink stay plain.
+func (s *Server) compressEvent(event Event) Event {
+	if !s.options().CompressBufferedEvents || event.compressed {
+		return event
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, event.Message); err != nil {
+		return event
+	}
+	if err := zw.Close(); err != nil || buf.Len() >= len(event.Message) {
+		return event
+	}
+	event.Message = buf.String()
+	event.compressed = true
+	return event
+}
+
+// decompressEvent undoes compressEvent.
+func decompressEvent(event Event) Event {
+	if !event.compressed {
+		return event
+	}
+	zr, err := gzip.NewReader(bytes.NewReader([]byte(event.Message)))
+	if err != nil {
+		log.Printf("Failed to decompress buffered event: %v", err)
+		return event
+	}
+	msg, err := io.ReadAll(zr)
+	if err != nil {
+		log.Printf("Failed to decompress buffered event: %v", err)
+		return event
+	}
+	event.Message = string(msg)
+	event.compressed = false
+	return event
+}
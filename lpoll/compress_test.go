@@ -0,0 +1,98 @@
+package lpoll
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jsonMessage returns a repetitive JSON array of n records, typical of the
+// payloads CompressBufferedEvents is meant for.
+func jsonMessage(n int) string {
+	var b strings.Builder
+	b.WriteString("[")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"id":%d,"status":"active","region":"eu-west-1","tags":["alpha","beta"]}`, i)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+func TestCompressBufferedEvents(t *testing.T) {
+	s := New(LpollOptions{CompressBufferedEvents: true})
+	msg := jsonMessage(100)
+	s.EnsureClient("c1")
+	if err := s.send("c1", Event{Message: msg, Time: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.RLock()
+	buffered := <-s.clientChannels["c1"].Channel
+	s.mu.RUnlock()
+	if !buffered.compressed || len(buffered.Message) >= len(msg) {
+		t.Fatalf("buffered message not compressed: %d bytes, plain %d", len(buffered.Message), len(msg))
+	}
+	if got := decompressEvent(buffered); got.compressed || got.Message != msg {
+		t.Fatal("decompressEvent did not restore the message")
+	}
+
+	if err := s.send("c1", Event{Message: msg, Time: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	w := serve(s, "GET", "/poll/c1", "")
+	var polled Event
+	if err := json.Unmarshal(w.Body.Bytes(), &polled); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || polled.Message != msg {
+		t.Fatalf("poll: got %d and a %d byte message, want 200 and the plain message", w.Code, len(polled.Message))
+	}
+}
+
+func TestCompressEventKeepsSmallMessagesPlain(t *testing.T) {
+	s := New(LpollOptions{CompressBufferedEvents: true})
+	if event := s.compressEvent(Event{Message: "hi"}); event.compressed || event.Message != "hi" {
+		t.Fatal("message that does not shrink was compressed")
+	}
+}
+
+// BenchmarkCompressBufferedEvents measures the heap held by a full client
+// channel, reported as buffered-B/msg, with and without compression.
+func BenchmarkCompressBufferedEvents(b *testing.B) {
+	const buffered = 1000
+	msg := jsonMessage(100)
+	for _, compress := range []bool{false, true} {
+		b.Run(fmt.Sprintf("compress=%v", compress), func(b *testing.B) {
+			var held int64
+			for i := 0; i < b.N; i++ {
+				s := New(LpollOptions{CompressBufferedEvents: compress})
+				s.EnsureClient("c1")
+				if err := s.ResizeClientChannel("c1", buffered); err != nil {
+					b.Fatal(err)
+				}
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				for j := 0; j < buffered; j++ {
+					// Copy so every event owns its message, as published ones do.
+					m := strings.Clone(msg)
+					if err := s.send("c1", Event{Message: m}); err != nil {
+						b.Fatal(err)
+					}
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				held += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+				runtime.KeepAlive(s)
+			}
+			b.ReportMetric(float64(held)/float64(b.N*buffered), "buffered-B/msg")
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package lpoll
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DrainAndClose shuts s down without losing buffered events: publishes are
+// rejected with ErrDraining, pollers get up to LpollOptions.DrainTimeout to
+// consume what is buffered, and then every client is evicted. The returned
+// error lists the clients whose events were not drained. Persisted client
+// registrations are kept, and saved once more with their delivered
+// cursors, so they can be restored on the next start.
+// Finally the cleanup loop and any HTTP server started by Listen are
+// stopped, so Listen returns.
+func (s *Server) DrainAndClose() error {
+	s.draining.Store(true)
+
+	deadline := time.Now().Add(s.options().DrainTimeout)
+	for len(s.undrainedClients()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	undrained := s.undrainedClients()
+
+	metas := make(map[string]ClientMeta, len(s.clientChannels))
+	s.mu.Lock()
+	for clientId, clientState := range s.clientChannels {
+		metas[clientId] = s.clientMeta(clientState)
+		delete(s.clientChannels, clientId)
+		s.emitChange(ClientEvicted, clientId)
+		closeChannel(clientState)
+	}
+	s.mu.Unlock()
+	for clientId, meta := range metas {
+		s.saveClientState(clientId, meta)
+	}
+	s.closeListeners()
+	log.Printf("Server closed, %d clients with undrained events", len(undrained))
+
+	if len(undrained) > 0 {
+		sort.Strings(undrained)
+		return fmt.Errorf("lpoll: events not drained for clients: %s", strings.Join(undrained, ", "))
+	}
+	return nil
+}
+
+// undrainedClients returns the IDs of clients that still have buffered events.
+func (s *Server) undrainedClients() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var ids []string
+	for clientId, clientState := range s.clientChannels {
+		if len(clientState.Channel) > 0 {
+			ids = append(ids, clientId)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,41 @@
+// Package echo mounts lpoll on an Echo router.
+package echo
+
+import (
+	"net/http"
+
+	echov4 "github.com/labstack/echo/v4"
+	"github.com/syosifov/lpoll/lpoll"
+)
+
+// AttachEchoHandler mounts the lpoll routes of s on e under prefix and
+// returns their group, so more middleware can be added to it. Requests are
+// served by s.Handler with the path rewritten to the lpoll route and the
+// clientId path parameter, if any; the query string is kept.
+func AttachEchoHandler(s *lpoll.Server, e *echov4.Echo, prefix string) *echov4.Group {
+	h := s.Handler()
+	g := e.Group(prefix)
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/poll"},
+		{http.MethodPost, "/publish"},
+		{http.MethodGet, "/ws"},
+	} {
+		g.Add(route.method, route.path, forward(h, route.path))
+		g.Add(route.method, route.path+"/:clientId", forward(h, route.path))
+	}
+	g.GET("/health", forward(h, "/health"))
+	return g
+}
+
+func forward(h http.Handler, path string) echov4.HandlerFunc {
+	return func(c echov4.Context) error {
+		r := c.Request().Clone(c.Request().Context())
+		r.URL.Path = path
+		if clientId := c.Param("clientId"); clientId != "" {
+			r.URL.Path += "/" + clientId
+		}
+		r.URL.RawPath = ""
+		h.ServeHTTP(c.Response(), r)
+		return nil
+	}
+}
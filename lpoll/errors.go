@@ -0,0 +1,68 @@
+package lpoll
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	lperrors "github.com/syosifov/lpoll/lpoll/errors"
+)
+
+// The sentinel errors of package lpoll/errors, re-exported for convenience.
+var (
+	ErrInvalidClientID  = lperrors.ErrInvalidClientID
+	ErrClientNotFound   = lperrors.ErrClientNotFound
+	ErrClientEvicted    = lperrors.ErrClientEvicted
+	ErrChannelFull      = lperrors.ErrChannelFull
+	ErrBufferFull       = lperrors.ErrBufferFull
+	ErrEventRejected    = lperrors.ErrEventRejected
+	ErrSchemaValidation = lperrors.ErrSchemaValidation
+	ErrDraining         = lperrors.ErrDraining
+	ErrNoEventStore     = lperrors.ErrNoEventStore
+	ErrEventStore       = lperrors.ErrEventStore
+	ErrReplayPartial    = lperrors.ErrReplayPartial
+	ErrMaxClients       = lperrors.ErrMaxClients
+	ErrClientCert       = lperrors.ErrClientCert
+	ErrClientIDCheck    = lperrors.ErrClientIDCheck
+	ErrNoPushProvider   = lperrors.ErrNoPushProvider
+	ErrNoPushToken      = lperrors.ErrNoPushToken
+)
+
+// statusForError maps an lpoll error to the HTTP status the handlers use.
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidClientID):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrClientCert):
+		return http.StatusForbidden
+	case errors.Is(err, ErrClientNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrClientEvicted):
+		return http.StatusGone
+	case errors.Is(err, ErrEventRejected), errors.Is(err, ErrSchemaValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrChannelFull), errors.Is(err, ErrBufferFull),
+		errors.Is(err, ErrReplayPartial), errors.Is(err, ErrDraining),
+		errors.Is(err, ErrMaxClients), errors.Is(err, ErrClientIDCheck),
+		errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrNoEventStore), errors.Is(err, ErrNoPushProvider):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError responds with the status for err. The messages the handlers
+// used before the sentinels existed are kept.
+func writeError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, ErrClientNotFound):
+		msg = "Client not found"
+	case errors.Is(err, ErrChannelFull):
+		msg = "Client channel is full, skipping event."
+	}
+	c.JSON(statusForError(err), gin.H{"error": msg})
+}
@@ -0,0 +1,24 @@
+// Package errors defines the sentinel errors returned by lpoll. Match them
+// with errors.Is, as most are returned wrapped with more detail.
+package errors
+
+import "errors"
+
+var (
+	ErrInvalidClientID  = errors.New("lpoll: invalid client ID")
+	ErrClientNotFound   = errors.New("lpoll: client not found")
+	ErrClientEvicted    = errors.New("lpoll: client evicted")
+	ErrChannelFull      = errors.New("lpoll: client channel is full")
+	ErrBufferFull       = errors.New("lpoll: client buffer is full")
+	ErrEventRejected    = errors.New("lpoll: event rejected")
+	ErrSchemaValidation = errors.New("lpoll: event does not match its schema")
+	ErrDraining         = errors.New("lpoll: server is draining")
+	ErrNoEventStore     = errors.New("lpoll: no event store configured")
+	ErrEventStore       = errors.New("lpoll: event store failed")
+	ErrReplayPartial    = errors.New("lpoll: replay stopped, client channel is full")
+	ErrMaxClients       = errors.New("lpoll: maximum number of clients reached")
+	ErrClientCert       = errors.New("lpoll: client certificate does not match client ID")
+	ErrClientIDCheck    = errors.New("lpoll: client ID check failed")
+	ErrNoPushProvider   = errors.New("lpoll: no push provider configured")
+	ErrNoPushToken      = errors.New("lpoll: client has no push token")
+)
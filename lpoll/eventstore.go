@@ -0,0 +1,203 @@
+package lpoll
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// EventStore keeps published events durable across crashes.
+type EventStore interface {
+	Append(clientId string, e Event) error
+	// Load returns the client's events with Seq > afterSeq, oldest first.
+	Load(clientId string, afterSeq uint64) ([]Event, error)
+	// Trim removes the client's events with Seq < beforeSeq.
+	Trim(clientId string, beforeSeq uint64) error
+}
+
+// lastSeq is seeded from the clock so sequence numbers keep increasing
+// across restarts and never collide with events already in the store.
+var lastSeq atomic.Uint64
+
+func init() {
+	lastSeq.Store(uint64(time.Now().UnixNano()))
+}
+
+func nextSeq() uint64 {
+	return lastSeq.Add(1)
+}
+
+// LimitedEventStore is implemented by event stores that can bound a Load.
+// loadStoredEvents then only loads as many events as fit the channel.
+type LimitedEventStore interface {
+	// LoadLimit is Load returning at most limit events.
+	LoadLimit(clientId string, afterSeq uint64, limit int) ([]Event, error)
+}
+
+// SetEventStore makes every publish append the event to store before it is
+// sent. Each client keeps a cursor at the last stored event delivered to it
+// by a poll or WebSocket, persisted through the state store; events after
+// it are loaded into its channel as it has room, so a client reconnecting,
+// restored after a restart or with a backlog larger than its channel gets
+// every undelivered event in order, at least once. A client registered
+// anew starts after the events stored so far. The "after" query parameter
+// of a first poll moves the cursor. Stored events are trimmed by retention
+// policies, see ConfigureRetention, and when their client is evicted by
+// the cleanup or merged away. It should be called once, before the
+// handlers start serving.
+func (s *Server) SetEventStore(store EventStore) {
+	s.eventStore = store
+}
+
+// resetStoreCursor points client, which just got an empty channel, at the
+// stored events after the last one delivered. Call it under the write lock.
+func (s *Server) resetStoreCursor(client *ClientState) {
+	client.storeSeq = atomic.LoadUint64(&client.deliveredSeq)
+	client.backlog = s.eventStore != nil
+}
+
+// deferToStore reports whether event must be left in the event store
+// because older stored events are still waiting for the client's channel.
+// Call it under the read lock.
+func deferToStore(client *ClientState, event Event) bool {
+	if event.Seq == 0 || !client.backlog {
+		return false
+	}
+	atomic.AddUint64(&client.storeSkips, 1)
+	return true
+}
+
+// markDelivered moves the client's delivered cursor to event.
+func markDelivered(client *ClientState, event Event) {
+	for {
+		delivered := atomic.LoadUint64(&client.deliveredSeq)
+		if event.Seq <= delivered || atomic.CompareAndSwapUint64(&client.deliveredSeq, delivered, event.Seq) {
+			return
+		}
+	}
+}
+
+// loadStoredEvents fills the client's channel with the stored events after
+// its cursor, or after the given after parameter if it is set, until the
+// channel is full or the backlog is empty. It returns right away when the
+// client has no backlog, so it is called before every receive.
+func (s *Server) loadStoredEvents(clientId, after string) {
+	if s.eventStore == nil {
+		return
+	}
+
+	if after != "" {
+		afterSeq, err := strconv.ParseUint(after, 10, 64)
+		if err != nil {
+			log.Printf("Ignoring invalid after=%q for client %s", after, clientId)
+		} else {
+			s.mu.Lock()
+			if client, ok := s.clientChannels[clientId]; ok && client.Channel != nil {
+				client.storeSeq = afterSeq
+				client.backlog = true
+			}
+			s.mu.Unlock()
+		}
+	}
+
+	for {
+		s.mu.RLock()
+		client, ok := s.clientChannels[clientId]
+		if !ok || client.Channel == nil || !client.backlog {
+			s.mu.RUnlock()
+			return
+		}
+		cursor := client.storeSeq
+		skips := atomic.LoadUint64(&client.storeSkips)
+		room := cap(client.Channel) - len(client.Channel)
+		s.mu.RUnlock()
+		if room == 0 {
+			return
+		}
+
+		var events []Event
+		var err error
+		if limited, ok := s.eventStore.(LimitedEventStore); ok {
+			events, err = limited.LoadLimit(clientId, cursor, room)
+		} else {
+			events, err = s.eventStore.Load(clientId, cursor)
+		}
+		if err != nil {
+			log.Printf("Failed to load stored events for client %s: %v", clientId, err)
+			return
+		}
+
+		// The load may be slow; the client may have been evicted, or
+		// another load may have moved the cursor, meanwhile. Sending under
+		// the write lock keeps publishes from slipping in between.
+		s.mu.Lock()
+		if s.clientChannels[clientId] != client || client.storeSeq != cursor {
+			s.mu.Unlock()
+			continue
+		}
+		sent := 0
+	send:
+		for _, event := range events {
+			select {
+			case client.Channel <- s.compressEvent(event):
+				client.storeSeq = event.Seq
+				sent++
+			default:
+				break send
+			}
+		}
+		full := sent < len(events) || len(client.Channel) == cap(client.Channel)
+		// Publishes deferred during the load may not be in events; load
+		// again to pick them up.
+		if !full && atomic.LoadUint64(&client.storeSkips) == skips {
+			client.backlog = false
+		}
+		done := full || !client.backlog
+		s.mu.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// Replay pushes the client's stored events with Time in [from, to] into its
+// channel, oldest first. If the channel fills up, it returns an error
+// wrapping ErrReplayPartial that reports how many events were replayed.
+func (s *Server) Replay(clientId string, from, to time.Time) error {
+	if s.eventStore == nil {
+		return ErrNoEventStore
+	}
+
+	if !s.ClientExists(clientId) {
+		return ErrClientNotFound
+	}
+
+	events, err := s.eventStore.Load(clientId, 0)
+	if err != nil {
+		return err
+	}
+
+	// The load may be slow; look the client up again and send under the
+	// read lock, as it may have been evicted or resized meanwhile.
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return ErrClientNotFound
+	}
+	replayed := 0
+	for _, event := range events {
+		if event.Time.Before(from) || event.Time.After(to) {
+			continue
+		}
+		select {
+		case client.Channel <- s.compressEvent(event):
+			replayed++
+		default:
+			return fmt.Errorf("%w: %d events replayed", ErrReplayPartial, replayed)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package lpoll
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore is an in-memory EventStore. onLoad, if set, runs at the start
+// of every Load.
+type memStore struct {
+	mu     sync.Mutex
+	events map[string][]Event
+	onLoad func()
+}
+
+func (m *memStore) Append(clientId string, e Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.events == nil {
+		m.events = make(map[string][]Event)
+	}
+	m.events[clientId] = append(m.events[clientId], e)
+	return nil
+}
+
+func (m *memStore) Load(clientId string, afterSeq uint64) ([]Event, error) {
+	if m.onLoad != nil {
+		m.onLoad()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []Event
+	for _, e := range m.events[clientId] {
+		if e.Seq > afterSeq {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (m *memStore) Trim(clientId string, beforeSeq uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []Event
+	for _, e := range m.events[clientId] {
+		if e.Seq >= beforeSeq {
+			kept = append(kept, e)
+		}
+	}
+	m.events[clientId] = kept
+	return nil
+}
+
+func TestReplayClientEvictedDuringLoad(t *testing.T) {
+	s := New(LpollOptions{})
+	st := &memStore{}
+	s.SetEventStore(st)
+	s.EnsureClient("a")
+	st.Append("a", Event{Message: "old", Time: time.Now(), Seq: 1})
+	st.onLoad = func() { evict(s, "a") }
+
+	err := s.Replay("a", time.Time{}, time.Now().Add(time.Hour))
+	if !errors.Is(err, ErrClientNotFound) {
+		t.Fatalf("got %v, want ErrClientNotFound", err)
+	}
+}
+
+func TestLoadStoredEventsClientEvictedDuringLoad(t *testing.T) {
+	s := New(LpollOptions{})
+	st := &memStore{}
+	s.SetEventStore(st)
+	st.Append("a", Event{Message: "old", Seq: 1})
+	st.onLoad = func() { evict(s, "a") }
+
+	// Registers "a" and loads its stored events, which evicts it first.
+	s.EnsureClient("a")
+}
+
+func TestReplay(t *testing.T) {
+	s := New(LpollOptions{})
+	st := &memStore{}
+	s.SetEventStore(st)
+	client := s.EnsureClient("a")
+	st.Append("a", Event{Message: "old", Time: time.Now(), Seq: 1})
+
+	if err := s.Replay("a", time.Time{}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if event := <-client.Channel; event.Message != "old" {
+		t.Fatalf("got %q", event.Message)
+	}
+}
+
+// memStateStore is an in-memory StateStore.
+type memStateStore struct {
+	mu    sync.Mutex
+	metas map[string]ClientMeta
+}
+
+func (m *memStateStore) Save(clientId string, meta ClientMeta) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.metas == nil {
+		m.metas = make(map[string]ClientMeta)
+	}
+	m.metas[clientId] = meta
+	return nil
+}
+
+func (m *memStateStore) Load(clientId string) (ClientMeta, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	meta, ok := m.metas[clientId]
+	return meta, ok, nil
+}
+
+func (m *memStateStore) Delete(clientId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.metas, clientId)
+	return nil
+}
+
+func (m *memStateStore) LoadAll() (map[string]ClientMeta, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	metas := make(map[string]ClientMeta, len(m.metas))
+	for clientId, meta := range m.metas {
+		metas[clientId] = meta
+	}
+	return metas, nil
+}
+
+// pollMessage polls clientId and returns the delivered message, or "" on
+// a 204.
+func pollMessage(t *testing.T, s *Server, target string) string {
+	t.Helper()
+	w := serve(s, "GET", target, "")
+	if w.Code == http.StatusNoContent {
+		return ""
+	}
+	var event Event
+	if err := json.Unmarshal(w.Body.Bytes(), &event); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("poll %s: got %d %s", target, w.Code, w.Body.String())
+	}
+	return event.Message
+}
+
+func TestReconnectAfterEvictionSkipsDeliveredEvents(t *testing.T) {
+	s := New(LpollOptions{})
+	s.SetGlobalTimeout(50 * time.Millisecond)
+	s.SetEventStore(&memStore{})
+	s.EnsureClient("c1")
+	for _, msg := range []string{"old1", "old2", "new"} {
+		if err := s.publish(t.Context(), "c1", Event{Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+		if got := pollMessage(t, s, "/poll/c1"); got != msg {
+			t.Fatalf("got %q, want %q", got, msg)
+		}
+	}
+
+	evict(s, "c1")
+	if got := pollMessage(t, s, "/poll/c1"); got != "" {
+		t.Fatalf("re-registered client got %q, want nothing", got)
+	}
+}
+
+func TestStoredBacklogServedAcrossPolls(t *testing.T) {
+	st := &memStore{}
+	states := &memStateStore{}
+	s := New(LpollOptions{})
+	s.SetEventStore(st)
+	if err := s.SetStateStore(states); err != nil {
+		t.Fatal(err)
+	}
+	s.EnsureClient("c1")
+	if err := s.publish(t.Context(), "c1", Event{Message: "delivered"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pollMessage(t, s, "/poll/c1"); got != "delivered" {
+		t.Fatalf("got %q", got)
+	}
+	s.publish(t.Context(), "c1", Event{Message: "e1"})
+	// The channel holds one event; the rest are only in the store.
+	for _, msg := range []string{"e2", "e3"} {
+		if err := s.publish(t.Context(), "c1", Event{Message: msg}); !errors.Is(err, ErrChannelFull) {
+			t.Fatalf("got %v, want ErrChannelFull", err)
+		}
+	}
+	if err := s.DrainAndClose(); err == nil {
+		t.Fatal("undrained client not reported")
+	}
+
+	// Restart with the same stores.
+	restarted := New(LpollOptions{})
+	restarted.SetGlobalTimeout(50 * time.Millisecond)
+	restarted.SetEventStore(st)
+	if err := restarted.SetStateStore(states); err != nil {
+		t.Fatal(err)
+	}
+	restarted.EnsureClient("c1")
+	// Published while the backlog is pending, so it must come last.
+	if err := restarted.publish(t.Context(), "c1", Event{Message: "e4"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"e1", "e2", "e3", "e4", ""} {
+		if got := pollMessage(t, restarted, "/poll/c1"); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestFirstPollAfterParameter(t *testing.T) {
+	st := &memStore{}
+	s := New(LpollOptions{})
+	s.SetGlobalTimeout(50 * time.Millisecond)
+	s.SetEventStore(st)
+	s.EnsureClient("c1")
+	for _, msg := range []string{"e1", "e2"} {
+		s.publish(t.Context(), "c1", Event{Message: msg})
+	}
+	events, _ := st.Load("c1", 0)
+
+	evict(s, "c1")
+	target := "/poll/c1?after=" + strconv.FormatUint(events[0].Seq, 10)
+	if got := pollMessage(t, s, target); got != "e2" {
+		t.Fatalf("got %q, want \"e2\"", got)
+	}
+}
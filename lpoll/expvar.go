@@ -0,0 +1,36 @@
+package lpoll
+
+import (
+	"expvar"
+	"log"
+)
+
+// serverStats are the counters AttachExpvarHandler exposes. They are
+// expvar.Int values so they can be published as they are.
+type serverStats struct {
+	eventsPublished expvar.Int
+	eventsDropped   expvar.Int
+	pollTimeouts    expvar.Int
+}
+
+// AttachExpvarHandler publishes s's counters under the "lpoll" expvar name,
+// served at GET /debug/vars by RegisterAdminRoutes and by expvar's handler
+// on http.DefaultServeMux. expvar names are process-wide, so only the first
+// server to call it is published.
+func (s *Server) AttachExpvarHandler() {
+	if expvar.Get("lpoll") != nil {
+		log.Printf("expvar \"lpoll\" is already published, skipping")
+		return
+	}
+
+	m := new(expvar.Map)
+	m.Set("activeClients", expvar.Func(func() any {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return len(s.clientChannels)
+	}))
+	m.Set("eventsPublished", &s.stats.eventsPublished)
+	m.Set("eventsDropped", &s.stats.eventsDropped)
+	m.Set("pollTimeouts", &s.stats.pollTimeouts)
+	expvar.Publish("lpoll", m)
+}
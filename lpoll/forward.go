@@ -0,0 +1,47 @@
+package lpoll
+
+import (
+	"context"
+	"log"
+)
+
+// ForwardTo moves every event from clientId's channel on s to the client
+// with the same ID on dst, registering it there if needed. Forwarded events
+// are no longer delivered by s; each one waits for room on dst. The pipe
+// stops when cancel is called or the source client is evicted; cancel is
+// safe to call more than once.
+func (s *Server) ForwardTo(dst *Server, clientId string) (cancel func()) {
+	s.mu.RLock()
+	client, ok := s.clientChannels[clientId]
+	var ch chan Event
+	if ok {
+		ch = client.Channel
+	}
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if !ok {
+		log.Printf("Not forwarding unknown client: %s", clientId)
+		return cancel
+	}
+	dst.EnsureClient(clientId)
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-ch:
+				if !ok {
+					return
+				}
+				s.received(client)
+				err := dst.PublishWithContext(ctx, clientId, decompressEvent(event))
+				if err != nil && ctx.Err() == nil {
+					log.Printf("Failed to forward event for client %s: %v", clientId, err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return cancel
+}
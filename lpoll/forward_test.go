@@ -0,0 +1,36 @@
+package lpoll
+
+import (
+	"testing"
+	"time"
+)
+
+func TestForwardToWaitsForRoom(t *testing.T) {
+	src, dst := New(LpollOptions{}), New(LpollOptions{})
+	src.EnsureClient("a")
+	cancel := src.ForwardTo(dst, "a")
+	defer cancel()
+
+	if !dst.ClientExists("a") {
+		t.Fatal("client was not registered on dst")
+	}
+	dstCh := dst.clientChannel(dst.EnsureClient("a"))
+
+	// dst's channel holds one event, so the second has to wait for room
+	// instead of being dropped.
+	for _, msg := range []string{"one", "two"} {
+		if err := src.PublishWithContext(t.Context(), "a", Event{Message: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"one", "two"} {
+		select {
+		case event := <-dstCh:
+			if event.Message != want {
+				t.Fatalf("got %q, want %q", event.Message, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q was not forwarded", want)
+		}
+	}
+}
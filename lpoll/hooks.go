@@ -0,0 +1,53 @@
+package lpoll
+
+import (
+	"context"
+	"reflect"
+)
+
+// EventHook observes events on their way through a server.
+type EventHook interface {
+	// OnPublish runs before an event is enqueued and may modify it. An
+	// error rejects the publish with ErrEventRejected.
+	OnPublish(ctx context.Context, clientId string, event *Event) error
+	// OnDeliver runs after an event was handed to the client.
+	OnDeliver(ctx context.Context, clientId string, event Event)
+}
+
+// AddEventHook appends hook to the hooks run, in registration order, on
+// every publish and delivery. OnPublish hooks run after the BeforePublish
+// hook. A hook that may be removed must be comparable, e.g. a pointer.
+func (s *Server) AddEventHook(hook EventHook) {
+	s.eventHooksMu.Lock()
+	s.eventHooks = append(s.eventHooks, hook)
+	s.eventHooksMu.Unlock()
+}
+
+// RemoveEventHook removes the first registered hook equal to hook. Hooks of
+// a non-comparable type, such as a struct value holding a map or func, are
+// never equal and cannot be removed.
+func (s *Server) RemoveEventHook(hook EventHook) {
+	if hook == nil || !reflect.TypeOf(hook).Comparable() {
+		return
+	}
+	s.eventHooksMu.Lock()
+	defer s.eventHooksMu.Unlock()
+	for i, h := range s.eventHooks {
+		if h == hook {
+			s.eventHooks = append(s.eventHooks[:i:i], s.eventHooks[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Server) loadEventHooks() []EventHook {
+	s.eventHooksMu.RLock()
+	defer s.eventHooksMu.RUnlock()
+	return s.eventHooks
+}
+
+func (s *Server) notifyDelivered(ctx context.Context, clientId string, event Event) {
+	for _, hook := range s.loadEventHooks() {
+		hook.OnDeliver(ctx, clientId, event)
+	}
+}
@@ -0,0 +1,53 @@
+package lpoll
+
+import (
+	"context"
+	"testing"
+)
+
+// deliveryCounter counts deliveries; as a pointer it is comparable.
+type deliveryCounter struct{ delivered int }
+
+func (h *deliveryCounter) OnPublish(ctx context.Context, clientId string, event *Event) error {
+	return nil
+}
+
+func (h *deliveryCounter) OnDeliver(ctx context.Context, clientId string, event Event) {
+	h.delivered++
+}
+
+// funcHook is not comparable, as it holds a func.
+type funcHook struct{ onDeliver func() }
+
+func (h funcHook) OnPublish(ctx context.Context, clientId string, event *Event) error {
+	return nil
+}
+
+func (h funcHook) OnDeliver(ctx context.Context, clientId string, event Event) {
+	h.onDeliver()
+}
+
+func TestRemoveEventHook(t *testing.T) {
+	s := New(LpollOptions{})
+	hook := &deliveryCounter{}
+	s.AddEventHook(hook)
+	s.notifyDelivered(t.Context(), "c1", Event{})
+	s.RemoveEventHook(hook)
+	s.notifyDelivered(t.Context(), "c1", Event{})
+	if hook.delivered != 1 {
+		t.Fatalf("hook ran %d times, want 1", hook.delivered)
+	}
+}
+
+func TestRemoveNonComparableEventHook(t *testing.T) {
+	s := New(LpollOptions{})
+	delivered := 0
+	hook := funcHook{onDeliver: func() { delivered++ }}
+	s.AddEventHook(hook)
+	// Must not panic comparing the func fields.
+	s.RemoveEventHook(hook)
+	s.notifyDelivered(t.Context(), "c1", Event{})
+	if delivered != 1 {
+		t.Fatalf("hook ran %d times, want 1", delivered)
+	}
+}
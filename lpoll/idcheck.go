@@ -0,0 +1,47 @@
+package lpoll
+
+import (
+	"fmt"
+	"time"
+)
+
+// checkNewClientID runs ClientIDExistsChecker for a client that is not
+// registered yet. Positive results are cached for ClientIDCacheTTL.
+func (s *Server) checkNewClientID(clientId string) error {
+	opts := s.options()
+	check := opts.ClientIDExistsChecker
+	if check == nil || s.ClientExists(clientId) {
+		return nil
+	}
+
+	s.idCacheMu.Lock()
+	expires, cached := s.idCache[clientId]
+	s.idCacheMu.Unlock()
+	if cached && time.Now().Before(expires) {
+		return nil
+	}
+
+	exists, err := check(clientId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrClientIDCheck, err)
+	}
+	if !exists {
+		return ErrClientNotFound
+	}
+
+	if opts.ClientIDCacheTTL > 0 {
+		s.idCacheMu.Lock()
+		if s.idCache == nil {
+			s.idCache = make(map[string]time.Time)
+		}
+		now := time.Now()
+		for id, exp := range s.idCache {
+			if now.After(exp) {
+				delete(s.idCache, id)
+			}
+		}
+		s.idCache[clientId] = now.Add(opts.ClientIDCacheTTL)
+		s.idCacheMu.Unlock()
+	}
+	return nil
+}
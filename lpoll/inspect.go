@@ -0,0 +1,84 @@
+package lpoll
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ClientSnapshot is a point-in-time copy of a client's state.
+type ClientSnapshot struct {
+	ClientID      string    `json:"clientId"`
+	QueueDepth    int       `json:"queueDepth"`
+	QueueCapacity int       `json:"queueCapacity"`
+	LastSeen      time.Time `json:"lastSeen"`
+	RegisteredAt  time.Time `json:"registeredAt"`
+	// Connected is false for clients restored from the state store that
+	// have not polled since.
+	Connected      bool   `json:"connected"`
+	PushToken      string `json:"pushToken,omitempty"`
+	TotalPublished uint64 `json:"totalPublished"`
+	// Events are the buffered events, oldest first.
+	Events []Event `json:"events,omitempty"`
+}
+
+// Inspect returns a snapshot of clientId, or ErrClientNotFound. The
+// buffered events are taken out and put back, like Checkpoint does.
+func (s *Server) Inspect(clientId string) (*ClientSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	return &ClientSnapshot{
+		ClientID:       clientId,
+		QueueDepth:     len(client.Channel),
+		QueueCapacity:  cap(client.Channel),
+		LastSeen:       client.LastSeen,
+		RegisteredAt:   client.RegisteredAt,
+		Connected:      client.Channel != nil,
+		PushToken:      client.PushToken,
+		TotalPublished: atomic.LoadUint64(&client.TotalPublished),
+		Events:         peekBuffered(clientId, client),
+	}, nil
+}
+
+// ConnectedSince returns when clientId was first registered.
+func (s *Server) ConnectedSince(clientId string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return time.Time{}, false
+	}
+	return client.RegisteredAt, true
+}
+
+// EventCount returns how many events were published to clientId since it
+// was registered.
+func (s *Server) EventCount(clientId string) (uint64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return 0, false
+	}
+	return atomic.LoadUint64(&client.TotalPublished), true
+}
+
+// PeekEvents returns up to the first n events buffered for clientId
+// without consuming them, or ErrClientNotFound. Like Inspect it takes the
+// events out and puts them back under the client lock.
+func (s *Server) PeekEvents(clientId string, n int) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	events := peekBuffered(clientId, client)
+	if n < len(events) {
+		events = events[:max(n, 0)]
+	}
+	return events, nil
+}
@@ -0,0 +1,72 @@
+package lpoll
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInspectBufferedEvents(t *testing.T) {
+	s := New(LpollOptions{})
+	client := s.EnsureClient("c1")
+	if err := s.send("c1", Event{Message: "buffered"}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := s.Inspect("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Events) != 1 || snapshot.Events[0].Message != "buffered" {
+		t.Fatalf("snapshot events %+v", snapshot.Events)
+	}
+	if snapshot.QueueDepth != 1 {
+		t.Fatalf("queue depth %d, want 1", snapshot.QueueDepth)
+	}
+	// Inspecting does not consume the event.
+	select {
+	case event := <-client.Channel:
+		if event.Message != "buffered" {
+			t.Fatalf("got %q", event.Message)
+		}
+	default:
+		t.Fatal("Inspect consumed the buffered event")
+	}
+
+	if _, err := s.Inspect("missing"); !errors.Is(err, ErrClientNotFound) {
+		t.Fatalf("got %v, want ErrClientNotFound", err)
+	}
+}
+
+func TestPeekEvents(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+	if err := s.ResizeClientChannel("c1", 3); err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []string{"a", "b", "c"} {
+		if err := s.send("c1", Event{Message: message}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := s.PeekEvents("c1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Message != "a" || events[1].Message != "b" {
+		t.Fatalf("peeked %+v", events)
+	}
+	s.mu.RLock()
+	ch := s.clientChannels["c1"].Channel
+	s.mu.RUnlock()
+	if len(ch) != 3 {
+		t.Fatalf("%d events left buffered, want 3", len(ch))
+	}
+	if event := <-ch; event.Message != "a" {
+		t.Fatalf("first event after peeking is %q", event.Message)
+	}
+
+	if _, err := s.PeekEvents("missing", 1); !errors.Is(err, ErrClientNotFound) {
+		t.Fatalf("got %v, want ErrClientNotFound", err)
+	}
+}
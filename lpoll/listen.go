@@ -0,0 +1,146 @@
+package lpoll
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes mounts the lpoll handlers on r, served by s. The routes
+// only carry a :clientId segment when the client ID is read from the path.
+func (s *Server) RegisterRoutes(r gin.IRoutes) {
+	attach := s.AttachGinMiddleware()
+	param := "/:clientId"
+	if s.options().ClientIDSource != PathParam {
+		param = ""
+	}
+	r.GET("/poll"+param, attach, PollHandler)
+	r.POST("/publish"+param, attach, PublishHandler)
+	r.GET("/ws"+param, attach, WebSocketHandler)
+	r.GET("/health", attach, HealthHandler)
+}
+
+// RegisterAdminRoutes mounts the operator endpoints on r. They are not part
+// of RegisterRoutes and should be mounted behind authentication.
+func (s *Server) RegisterAdminRoutes(r gin.IRoutes) {
+	attach := s.AttachGinMiddleware()
+	r.POST("/admin/benchmark", attach, BenchmarkHandler)
+	r.GET("/clients/:clientId/metadata", attach, ClientMetadataHandler)
+	r.PUT("/clients/:clientId/metadata/:key", attach, SetClientMetadataHandler)
+	r.DELETE("/clients/:clientId/metadata/:key", attach, DeleteClientMetadataHandler)
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+}
+
+// Listen serves the lpoll routes on addr with Gin's default middleware
+// (logger, recovery) and runs the inactive client cleanup. It blocks until
+// DrainAndClose closes the server, then returns nil, or until the HTTP
+// server fails. RequireClientCert needs ListenTLS.
+func (s *Server) Listen(addr string) error {
+	if s.options().RequireClientCert {
+		return errors.New("lpoll: RequireClientCert needs ListenTLS")
+	}
+	return s.serve(addr, func(srv *http.Server) error { return srv.ListenAndServe() })
+}
+
+// ListenTLS is Listen over HTTPS with the given certificate and key files.
+// With RequireClientCert, clients must present a certificate signed by
+// ClientCACert.
+func (s *Server) ListenTLS(addr, certFile, keyFile string) error {
+	return s.serve(addr, func(srv *http.Server) error { return srv.ListenAndServeTLS(certFile, keyFile) })
+}
+
+func (s *Server) serve(addr string, listen func(*http.Server) error) error {
+	srv, err := s.httpServer(addr)
+	if err != nil {
+		return err
+	}
+
+	s.listenMu.Lock()
+	select {
+	case <-s.closed:
+		s.listenMu.Unlock()
+		return http.ErrServerClosed
+	default:
+	}
+	s.httpServers = append(s.httpServers, srv)
+	s.listenMu.Unlock()
+
+	stop := make(chan struct{})
+	cleanupDone := make(chan struct{})
+	go func() {
+		defer close(cleanupDone)
+		s.cleanupLoop(stop)
+	}()
+
+	err = listen(srv)
+	close(stop)
+	<-cleanupDone
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// shutdownTimeout bounds how long DrainAndClose waits for the HTTP servers
+// to finish their requests before closing them.
+const shutdownTimeout = 5 * time.Second
+
+// closeListeners stops the cleanup loops and the HTTP servers started by
+// Listen, which then return.
+func (s *Server) closeListeners() {
+	s.listenMu.Lock()
+	s.closeOnce.Do(func() { close(s.closed) })
+	servers := s.httpServers
+	s.httpServers = nil
+	s.listenMu.Unlock()
+
+	for _, srv := range servers {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := srv.Shutdown(ctx); err != nil {
+			srv.Close()
+		}
+		cancel()
+	}
+}
+
+// Handler returns an http.Handler serving the lpoll routes at the root,
+// with Gin's default middleware. It is how other routers mount lpoll.
+func (s *Server) Handler() http.Handler {
+	engine := gin.Default()
+	s.RegisterRoutes(engine)
+	return engine
+}
+
+func (s *Server) httpServer(addr string) (*http.Server, error) {
+	tlsConfig, err := clientCertTLSConfig(s.options())
+	if err != nil {
+		return nil, err
+	}
+	return &http.Server{
+		Addr:      addr,
+		Handler:   s.withRequestTimeout(s.Handler()),
+		TLSConfig: tlsConfig,
+	}, nil
+}
+
+// withRequestTimeout caps every request at LpollOptions.RequestTimeout,
+// except the long-lived poll and WebSocket requests.
+func (s *Server) withRequestTimeout(h http.Handler) http.Handler {
+	d := s.options().RequestTimeout
+	if d <= 0 {
+		return h
+	}
+	timeout := http.TimeoutHandler(h, d, "request timed out")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/poll") || strings.HasPrefix(r.URL.Path, "/ws") {
+			h.ServeHTTP(w, r)
+			return
+		}
+		timeout.ServeHTTP(w, r)
+	})
+}
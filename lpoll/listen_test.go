@@ -0,0 +1,71 @@
+package lpoll
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListenReturnsAfterDrainAndClose(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s := New(LpollOptions{})
+	done := make(chan error, 1)
+	go func() { done <- s.Listen(addr) }()
+
+	// Wait for the listener to come up.
+	for i := 0; ; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := s.DrainAndClose(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Listen returned %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Listen did not return after DrainAndClose")
+	}
+	if err := s.Listen(addr); err == nil {
+		t.Fatal("Listen on a closed server succeeded")
+	}
+}
+
+func TestRequestTimeoutSkipsPolls(t *testing.T) {
+	s := New(LpollOptions{RequestTimeout: 20 * time.Millisecond})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	h := s.withRequestTimeout(slow)
+
+	for path, want := range map[string]int{
+		"/poll/c1":    http.StatusOK,
+		"/ws/c1":      http.StatusOK,
+		"/publish/c1": http.StatusServiceUnavailable,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("%s: got %d, want %d", path, w.Code, want)
+		}
+	}
+}
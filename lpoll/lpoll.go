@@ -1,83 +1,380 @@
 package lpoll
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"golang.org/x/time/rate"
 )
 
 type Event struct {
 	Message string    `json:"message"`
 	Time    time.Time `json:"time"`
+	Schema  string    `json:"schema,omitempty"`
+	Source  string    `json:"source,omitempty"`
+	Type    string    `json:"type,omitempty"`
+	// PublishedAt is when the event entered the publish path.
+	PublishedAt time.Time `json:"publishedAt"`
+	// Seq orders the events of a client; it is only set when an EventStore
+	// is in use.
+	Seq uint64 `json:"seq,omitempty"`
+	// TraceID and SpanID identify the span that published the event, taken
+	// from the publish request's traceparent header.
+	TraceID string `json:"traceId,omitempty"`
+	SpanID  string `json:"spanId,omitempty"`
+
+	// compressed marks a Message gzipped while buffered, see
+	// CompressBufferedEvents.
+	compressed bool
 }
 
 // ClientState holds the channel and a timestamp for a specific client.
 type ClientState struct {
 	Channel  chan Event
 	LastSeen time.Time
+	// RegisteredAt is when the client was first registered.
+	RegisteredAt time.Time
+	// PushToken is the device token used when the client cannot be reached
+	// over its channel.
+	PushToken string
+	// TotalPublished counts the events published to the client since it
+	// was registered. Access it atomically.
+	TotalPublished uint64
+
+	// lastCacheable is the last delivered event of a cacheable type.
+	lastCacheable *Event
+	// limiter throttles deliveries, see Throttle.
+	limiter *rate.Limiter
+	// metadata holds the SetClientMetadata values.
+	metadata map[string]string
+	// persistedAt is when the client was last saved to the state store.
+	persistedAt time.Time
+
+	// deliveredSeq is the Seq of the last stored event delivered to the
+	// client. Access it atomically.
+	deliveredSeq uint64
+	// storeSeq is the Seq of the last stored event loaded into Channel;
+	// loadStoredEvents continues after it.
+	storeSeq uint64
+	// backlog is set while the event store may hold events for the client
+	// that are not in Channel yet. Stored publishes are then left to
+	// loadStoredEvents, keeping their order, and counted in storeSkips
+	// (accessed atomically). storeSeq and backlog change under the write lock.
+	backlog    bool
+	storeSkips uint64
+	// swapped is closed when Channel is replaced or closed, and room is
+	// signalled when an event is taken from it; blocked publishers wait on
+	// both. See watchChannel.
+	swapped chan struct{}
+	room    chan struct{}
 }
 
-var (
+// Server is one lpoll instance with its own set of clients. Several servers
+// can be mounted on the same Gin router, see AttachGinMiddleware.
+type Server struct {
+	opts LpollOptions
+	// optsMu guards opts, which GracefulRestart may replace.
+	optsMu    sync.RWMutex
+	startedAt time.Time
+
 	// clientChannels maps a client ID to its state.
 	clientChannels map[string]*ClientState
 	// mutex for safe concurrent access to the clientChannels map.
 	mu sync.RWMutex
 	// Define the timeout duration for client inactivity.
-	clientTimeout = 1 * time.Minute
-)
+	clientTimeout time.Duration
+	// pollTimeout is how long a poll waits for an event.
+	pollTimeout time.Duration
+	// timeoutsMu guards clientTimeout and pollTimeout.
+	timeoutsMu sync.RWMutex
+	// cleanupMu serializes cleanup passes.
+	cleanupMu sync.Mutex
+	// draining is set by DrainAndClose to reject new publishes.
+	draining atomic.Bool
+	// closed is closed by DrainAndClose to stop Listen and the cleanup loop.
+	closed    chan struct{}
+	closeOnce sync.Once
+	// httpServers are the servers started by Listen and ListenTLS.
+	httpServers []*http.Server
+	listenMu    sync.Mutex
+
+	// stateStore is optional; nil means registrations are kept in memory only.
+	stateStore StateStore
+	// eventStore is optional; nil means events only live in the channels.
+	eventStore EventStore
+
+	// schemas maps a schema name to its compiled JSON Schema.
+	schemas   map[string]*jsonschema.Schema
+	schemasMu sync.RWMutex
+
+	// taps are the server-side subscribers registered with Subscribe.
+	taps   []*tap
+	tapsMu sync.RWMutex
+
+	// beforePublish transforms every event before it is enqueued.
+	beforePublish   func(Event) (Event, error)
+	beforePublishMu sync.RWMutex
+
+	pushProvider   PushProvider
+	pushProviderMu sync.RWMutex
+
+	eventHooks   []EventHook
+	eventHooksMu sync.RWMutex
+
+	middleware   []Middleware
+	middlewareMu sync.RWMutex
+
+	stats serverStats
+
+	// changeSubscribers are the channels returned by SubscribeChanges.
+	changeSubscribers []chan ClientEvent
+	changesMu         sync.RWMutex
+
+	// presence maps a client ID to its SubscribePresence state.
+	presence   map[string]*presence
+	presenceMu sync.Mutex
 
-func init() {
-	clientChannels = make(map[string]*ClientState)
+	// idCache maps client IDs ClientIDExistsChecker accepted to when the
+	// answer expires.
+	idCache   map[string]time.Time
+	idCacheMu sync.Mutex
+
+	metricsSubs map[<-chan ServerMetrics]*metricsSubscription
+	metricsMu   sync.Mutex
+
+	// groups are the RoundRobinGroup worker sets by name.
+	groups   map[string]*roundRobinGroup
+	groupsMu sync.RWMutex
+
+	// aliases maps an AddClientAlias alias to its canonical client ID.
+	aliases   map[string]string
+	aliasesMu sync.RWMutex
+
+	// retention maps a client ID to its ConfigureRetention policy, and
+	// retained to the index the policy in effect is enforced on.
+	retention   map[string]RetentionPolicy
+	retained    map[string]*retainedEvents
+	retentionMu sync.RWMutex
+}
+
+// defaultServer serves the handlers when no server was attached to the
+// request with AttachGinMiddleware.
+var defaultServer = New(LpollOptions{})
+
+// New returns a server with no clients configured by opts.
+func New(opts LpollOptions) *Server {
+	return &Server{
+		opts:           opts,
+		startedAt:      time.Now(),
+		clientChannels: make(map[string]*ClientState),
+		clientTimeout:  1 * time.Minute,
+		pollTimeout:    30 * time.Second,
+		schemas:        make(map[string]*jsonschema.Schema),
+		closed:         make(chan struct{}),
+	}
+}
+
+// serverKey is the Gin context key AttachGinMiddleware stores the server under.
+const serverKey = "lpoll.server"
+
+// AttachGinMiddleware returns a middleware that makes the handlers after it
+// use s instead of the default server.
+func (s *Server) AttachGinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(serverKey, s)
+		c.Next()
+	}
+}
+
+// serverFrom returns the server attached to c, or the default server.
+func serverFrom(c *gin.Context) *Server {
+	if v, ok := c.Get(serverKey); ok {
+		if s, ok := v.(*Server); ok {
+			return s
+		}
+	}
+	return defaultServer
 }
 
 func PollHandler(c *gin.Context) {
-	clientId := c.Param("clientId")
-	if clientId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "clientId is required"})
+	s := serverFrom(c)
+	// Read once so a timeout or options change does not affect this poll.
+	pollTimeout, _ := s.timeouts()
+	opts := s.options()
+	clientId, err := extractClientID(c, opts)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if canonicalId := s.resolveAlias(clientId); canonicalId != clientId {
+		redirectToCanonical(c, opts, canonicalId)
+		return
+	}
+
+	if err := s.checkNewClientID(clientId); err != nil {
+		writeError(c, err)
+		return
+	}
+	client, firstConnect, err := s.connectClient(clientId, true)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	after := ""
+	if firstConnect {
+		after = c.Query("after")
+	}
+	s.loadStoredEvents(clientId, after)
+
+	if s.notModified(c, client) {
+		return
+	}
+
+	pollCtx, cancel := context.WithTimeout(c.Request.Context(), pollTimeout)
+	defer cancel()
+
+	s.setPolling(clientId, true)
+	defer s.setPolling(clientId, false)
+
+	// A throttled client waits for its limiter before taking an event, so
+	// the event stays buffered until it may be delivered.
+	limiter := s.clientLimiter(client)
+	if limiter != nil && !waitForToken(pollCtx, limiter) {
+		s.pollTimedOut(c, clientId)
 		return
 	}
 
-	mu.Lock()
-	client, ok := clientChannels[clientId]
+	for {
+		ch, swapped := s.watchChannel(client)
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				// The client was evicted while this poll was waiting.
+				writeError(c, ErrClientEvicted)
+				return
+			}
+			s.received(client)
+			if limiter != nil {
+				// Only a delivery uses up the token.
+				limiter.Reserve()
+			}
+			event = decompressEvent(event)
+			s.setCacheHeaders(c, client, event)
+			c.JSON(http.StatusOK, event)
+			markDelivered(client, event)
+			traceDelivery(c.Request.Context(), clientId, event)
+			s.notifyDelivered(c.Request.Context(), clientId, event)
+			if opts.OnPollDelivery != nil {
+				opts.OnPollDelivery(clientId, event, time.Since(event.PublishedAt))
+			}
+			return
+		case <-swapped:
+			// ResizeClientChannel replaced the channel; wait on the new one.
+		case <-pollCtx.Done():
+			s.pollTimedOut(c, clientId)
+			return
+		}
+	}
+}
+
+func (s *Server) pollTimedOut(c *gin.Context, clientId string) {
+	c.JSON(http.StatusNoContent, nil)
+	s.stats.pollTimeouts.Add(1)
+	log.Printf("Poll timeout for client: %s", clientId)
+}
+
+// connectClient registers clientId, or refreshes its LastSeen if it is
+// already registered. firstConnect reports whether the client's channel was
+// created by this call. With enforceMax, a new client beyond MaxClients is
+// rejected with ErrMaxClients.
+func (s *Server) connectClient(clientId string, enforceMax bool) (client *ClientState, firstConnect bool, err error) {
+	opts := s.options()
+	s.mu.Lock()
+	client, ok := s.clientChannels[clientId]
+	if !ok && enforceMax && opts.MaxClients > 0 && len(s.clientChannels) >= opts.MaxClients {
+		s.mu.Unlock()
+		log.Printf("Client rejected, max clients reached: %s", clientId)
+		if opts.OnMaxClientsReached != nil {
+			opts.OnMaxClientsReached(clientId)
+		}
+		return nil, false, ErrMaxClients
+	}
+	firstConnect = !ok || client.Channel == nil
 	if !ok {
 		clientChan := make(chan Event, 1)
+		now := time.Now()
 		client = &ClientState{
-			Channel:  clientChan,
-			LastSeen: time.Now(),
+			Channel:      clientChan,
+			LastSeen:     now,
+			RegisteredAt: now,
+			deliveredSeq: lastSeq.Load(),
 		}
-		clientChannels[clientId] = client
+		s.resetStoreCursor(client)
+		s.clientChannels[clientId] = client
 		log.Printf("Client subscribed: %s", clientId)
+		s.emitChange(ClientRegistered, clientId)
+	} else if client.Channel == nil {
+		// Restored from the state store; the channel is created on first poll.
+		client.Channel = make(chan Event, 1)
+		client.LastSeen = time.Now()
+		s.resetStoreCursor(client)
+		log.Printf("Client restored: %s", clientId)
 	} else {
 		client.LastSeen = time.Now()
 		log.Printf("Client reconnected: %s", clientId)
 	}
-	mu.Unlock()
+	// Persisting every poll would cost a store write per request; a
+	// LastSeen up to half a client timeout old is enough for a restart.
+	_, clientTimeout := s.timeouts()
+	persist := firstConnect || time.Since(client.persistedAt) > clientTimeout/2
+	if persist {
+		client.persistedAt = client.LastSeen
+	}
+	meta := s.clientMeta(client)
+	s.mu.Unlock()
+	if persist {
+		s.saveClientState(clientId, meta)
+	}
+	return client, firstConnect, nil
+}
 
-	timeout := time.After(30 * time.Second)
+// ClientExists reports whether clientId is registered.
+func (s *Server) ClientExists(clientId string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.clientChannels[clientId]
+	return ok
+}
 
-	select {
-	case event := <-client.Channel:
-		c.JSON(http.StatusOK, event)
-		return
-	case <-timeout:
-		c.JSON(http.StatusNoContent, nil)
-		log.Printf("Poll timeout for client: %s", clientId)
-		return
-	}
+// EnsureClient registers clientId the same way PollHandler does, without
+// waiting for an event, and returns its state. MaxClients does not apply.
+func (s *Server) EnsureClient(clientId string) *ClientState {
+	client, _, _ := s.connectClient(clientId, false)
+	s.loadStoredEvents(clientId, "")
+	return client
 }
 
 func PublishHandler(c *gin.Context) {
-	clientId := c.Param("clientId")
-	if clientId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "clientId is required"})
+	s := serverFrom(c)
+	clientId, err := extractClientID(c, s.options())
+	if err != nil {
+		writeError(c, err)
 		return
 	}
+	clientId = s.resolveAlias(clientId)
 
 	var req struct {
 		Message string `json:"message" binding:"required"`
+		Schema  string `json:"schema"`
+		Type    string `json:"type"`
+		Source  string `json:"source"`
 	}
 
 	if err := c.BindJSON(&req); err != nil {
@@ -85,38 +382,188 @@ func PublishHandler(c *gin.Context) {
 		return
 	}
 
-	mu.RLock()
-	client, ok := clientChannels[clientId]
-	mu.RUnlock()
+	event := Event{Message: req.Message, Time: time.Now(), Schema: req.Schema, Type: req.Type, Source: req.Source}
+	event = withTraceParent(c.Request.Header, event)
+	if err := s.checkStrict(event); err != nil {
+		writeError(c, err)
+		return
+	}
+	if err := s.validateSchema(event); err != nil {
+		writeError(c, err)
+		return
+	}
 
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+	if s.options().LazyClientCreation && !s.ClientExists(clientId) {
+		if err := s.checkNewClientID(clientId); err != nil {
+			writeError(c, err)
+			return
+		}
+		if _, _, err := s.connectClient(clientId, true); err != nil {
+			writeError(c, err)
+			return
+		}
+		s.loadStoredEvents(clientId, "")
+	}
+
+	if err := s.publish(c.Request.Context(), clientId, event); err != nil {
+		if statusForError(err) == http.StatusInternalServerError {
+			log.Printf("Failed to publish to client %s: %v", clientId, err)
+		}
+		writeError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Event published."})
+}
+
+// publish does a non-blocking send of event to the client's channel.
+func (s *Server) publish(ctx context.Context, clientId string, event Event) error {
+	event, err := s.preparePublish(ctx, clientId, event)
+	if err != nil {
+		return err
+	}
+	return s.runMiddleware(clientId, event, func(event Event) error {
+		return s.send(clientId, event)
+	})
+}
 
+// send does the non-blocking channel send at the end of publish.
+func (s *Server) send(clientId string, event Event) error {
+	// Send under the read lock so an eviction cannot close the channel
+	// mid-send; the send never blocks.
+	s.mu.RLock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		s.mu.RUnlock()
+		return ErrClientNotFound
+	}
+	if deferToStore(client, event) {
+		// Queued behind the stored backlog; loadStoredEvents delivers it.
+		queueDepth := len(client.Channel)
+		atomic.AddUint64(&client.TotalPublished, 1)
+		s.mu.RUnlock()
+		s.stats.eventsPublished.Add(1)
+		s.notifyTaps(clientId, event)
+		if onSuccess := s.options().OnPublishSuccess; onSuccess != nil {
+			onSuccess(clientId, event, queueDepth)
+		}
+		return nil
+	}
 	select {
-	case client.Channel <- Event{Message: req.Message, Time: time.Now()}:
-		c.JSON(http.StatusOK, gin.H{"message": "Event published."})
+	case client.Channel <- s.compressEvent(event):
+		queueDepth := len(client.Channel)
+		atomic.AddUint64(&client.TotalPublished, 1)
+		s.mu.RUnlock()
+		s.stats.eventsPublished.Add(1)
+		s.notifyTaps(clientId, event)
+		if onSuccess := s.options().OnPublishSuccess; onSuccess != nil {
+			onSuccess(clientId, event, queueDepth)
+		}
+		return nil
 	default:
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Client channel is full, skipping event."})
+		// Restored clients that have not polled yet have no channel and
+		// also end up here.
+		pushToken := client.PushToken
+		s.mu.RUnlock()
+		s.stats.eventsDropped.Add(1)
+		s.sendPush(clientId, pushToken, event)
+		return ErrChannelFull
+	}
+}
+
+// preparePublish runs the publish hooks on event and appends it to the
+// event store, returning the event to enqueue.
+func (s *Server) preparePublish(ctx context.Context, clientId string, event Event) (Event, error) {
+	if s.draining.Load() {
+		return event, ErrDraining
+	}
+
+	if !s.ClientExists(clientId) {
+		return event, ErrClientNotFound
+	}
+	if event.PublishedAt.IsZero() {
+		event.PublishedAt = time.Now()
+	}
+
+	if hook := s.loadBeforePublish(); hook != nil {
+		var err error
+		if event, err = hook(event); err != nil {
+			return event, fmt.Errorf("%w: %v", ErrEventRejected, err)
+		}
+	}
+	for _, hook := range s.loadEventHooks() {
+		if err := hook.OnPublish(ctx, clientId, &event); err != nil {
+			return event, fmt.Errorf("%w: %v", ErrEventRejected, err)
+		}
+	}
+
+	if s.eventStore != nil {
+		event.Seq = nextSeq()
+		if err := s.eventStore.Append(clientId, event); err != nil {
+			return event, fmt.Errorf("%w: %v", ErrEventStore, err)
+		}
+		s.applyRetention(clientId, &event)
 	}
+	return event, nil
 }
 
+// CleanUpInactiveClients runs the cleanup loop of the default server.
 func CleanUpInactiveClients() {
+	defaultServer.CleanUpInactiveClients()
+}
+
+// CleanUpInactiveClients evicts inactive clients every minute until the
+// server is closed by DrainAndClose.
+func (s *Server) CleanUpInactiveClients() {
+	s.cleanupLoop(nil)
+}
+
+// cleanupLoop is CleanUpInactiveClients, which also returns when stop is
+// closed.
+func (s *Server) cleanupLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
 	for {
-		time.Sleep(1 * time.Minute) // Check for inactive clients every minute.
-
-		mu.Lock()
-		for clientId, clientState := range clientChannels {
-			// Check if the client's last seen time is older than the timeout.
-			if time.Since(clientState.LastSeen) > clientTimeout {
-				delete(clientChannels, clientId)
-				log.Printf("Cleaned up inactive client: %s", clientId)
-				log.Printf("Active clients remaining: %d", len(clientChannels))
-				// Close the channel to release resources.
-				close(clientState.Channel)
-			}
+		select {
+		case <-ticker.C:
+			s.CleanupNow()
+		case <-s.closed:
+			return
+		case <-stop:
+			return
+		}
+	}
+}
+
+// CleanupNow runs one cleanup pass synchronously and returns the number of
+// evicted clients. It is safe to call while CleanUpInactiveClients runs.
+func (s *Server) CleanupNow() (evicted int) {
+	s.cleanupMu.Lock()
+	defer s.cleanupMu.Unlock()
+	// Runs after the client lock below is released.
+	defer s.ApplyRetentionPolicies()
+
+	_, clientTimeout := s.timeouts()
+
+	var evictedIds []string
+	s.mu.Lock()
+	seq := lastSeq.Load()
+	for clientId, clientState := range s.clientChannels {
+		// Check if the client's last seen time is older than the timeout.
+		if time.Since(clientState.LastSeen) > clientTimeout {
+			delete(s.clientChannels, clientId)
+			s.emitChange(ClientEvicted, clientId)
+			log.Printf("Cleaned up inactive client: %s", clientId)
+			log.Printf("Active clients remaining: %d", len(s.clientChannels))
+			// Close the channel to release resources.
+			closeChannel(clientState)
+			evictedIds = append(evictedIds, clientId)
 		}
-		mu.Unlock()
 	}
+	s.mu.Unlock()
+
+	// Store I/O happens outside the client lock.
+	for _, clientId := range evictedIds {
+		s.forgetClient(clientId, seq)
+	}
+	return len(evictedIds)
 }
@@ -0,0 +1,61 @@
+package lpoll
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serve runs one request against s's routes.
+func serve(s *Server, method, target, body string) *httptest.ResponseRecorder {
+	engine := gin.New()
+	s.RegisterRoutes(engine)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestLazyPublishRunsClientIDChecker(t *testing.T) {
+	s := New(LpollOptions{
+		LazyClientCreation: true,
+		ClientIDExistsChecker: func(clientId string) (bool, error) {
+			return clientId == "known", nil
+		},
+	})
+
+	if w := serve(s, "POST", "/publish/unknown", `{"message":"hi"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown client: got %d, want 404", w.Code)
+	}
+	if s.ClientExists("unknown") {
+		t.Fatal("rejected client was registered")
+	}
+	if w := serve(s, "POST", "/publish/known", `{"message":"hi"}`); w.Code != http.StatusOK {
+		t.Fatalf("known client: got %d, want 200", w.Code)
+	}
+}
+
+func TestAliasRedirectQueryParam(t *testing.T) {
+	s := New(LpollOptions{ClientIDSource: QueryParam})
+	s.EnsureClient("canon")
+	if err := s.AddClientAlias("al", "canon"); err != nil {
+		t.Fatal(err)
+	}
+	w := serve(s, "GET", "/poll?clientId=al", "")
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("got %d, want 301", w.Code)
+	}
+	if loc := w.Header().Get("Location"); loc != "poll?clientId=canon" {
+		t.Fatalf("Location %q, want \"poll?clientId=canon\"", loc)
+	}
+}
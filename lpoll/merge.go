@@ -0,0 +1,119 @@
+package lpoll
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// mergeTimeout bounds how long MergeClients waits for the sources to drain.
+const mergeTimeout = 30 * time.Second
+
+// MergeClients registers mergedId and relays the buffered and incoming
+// events of every client in ids to it. Once the sources are drained, or
+// after mergeTimeout, the sources are deregistered and MergeClients returns.
+// It fails without changing anything if a source does not exist.
+func (s *Server) MergeClients(ids []string, mergedId string) error {
+	s.mu.RLock()
+	sources := make(map[string]chan Event, len(ids))
+	clients := make(map[string]*ClientState, len(ids))
+	for _, id := range ids {
+		client, ok := s.clientChannels[id]
+		if !ok {
+			s.mu.RUnlock()
+			return fmt.Errorf("%w: %s", ErrClientNotFound, id)
+		}
+		if id != mergedId {
+			sources[id] = client.Channel
+			clients[id] = client
+		}
+	}
+	s.mu.RUnlock()
+
+	s.EnsureClient(mergedId)
+	ctx, cancel := context.WithTimeout(context.Background(), mergeTimeout)
+	defer cancel()
+
+	var relays sync.WaitGroup
+	for id, ch := range sources {
+		if ch == nil {
+			// Restored and never polled, so nothing is buffered.
+			continue
+		}
+		relays.Add(1)
+		go func(client *ClientState, ch chan Event) {
+			defer relays.Done()
+			for {
+				select {
+				case event, ok := <-ch:
+					if !ok {
+						return
+					}
+					s.received(client)
+					// Look the merged client up for every send; it may be
+					// evicted or resized while the merge runs.
+					if _, err := s.sendBlocking(ctx, mergedId, event); err != nil {
+						if ctx.Err() == nil {
+							log.Printf("Stopped merging into client %s: %v", mergedId, err)
+						}
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(clients[id], ch)
+	}
+
+	relaysDone := make(chan struct{})
+	go func() {
+		relays.Wait()
+		close(relaysDone)
+	}()
+
+	// Wait for the relays to empty the sources before deregistering them.
+	drained := time.NewTicker(50 * time.Millisecond)
+	defer drained.Stop()
+wait:
+	for {
+		select {
+		case <-drained.C:
+			empty := true
+			for _, ch := range sources {
+				if len(ch) > 0 {
+					empty = false
+					break
+				}
+			}
+			if empty {
+				break wait
+			}
+		case <-relaysDone:
+			// Every relay stopped, e.g. because mergedId was evicted.
+			break wait
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	s.mu.Lock()
+	seq := lastSeq.Load()
+	for id, ch := range sources {
+		// The source may have been evicted meanwhile.
+		if client, ok := s.clientChannels[id]; ok && client.Channel == ch {
+			delete(s.clientChannels, id)
+			s.emitChange(ClientEvicted, id)
+			closeChannel(client)
+		}
+	}
+	s.mu.Unlock()
+	for id := range sources {
+		s.forgetClient(id, seq)
+	}
+
+	<-relaysDone
+	log.Printf("Merged %d clients into %s", len(sources), mergedId)
+	return nil
+}
@@ -0,0 +1,68 @@
+package lpoll
+
+import (
+	"testing"
+	"time"
+)
+
+// evict removes clientId the way CleanupNow does.
+func evict(s *Server, clientId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if client, ok := s.clientChannels[clientId]; ok {
+		delete(s.clientChannels, clientId)
+		closeChannel(client)
+	}
+}
+
+func TestMergeClientsMergedEvicted(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("a")
+	s.EnsureClient("m")
+	if err := s.publish(t.Context(), "a", Event{Message: "from a"}); err != nil {
+		t.Fatal(err)
+	}
+	// Fill the merged channel so the relay has to wait.
+	if err := s.publish(t.Context(), "m", Event{Message: "own"}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.MergeClients([]string{"a"}, "m") }()
+	time.Sleep(50 * time.Millisecond)
+	evict(s, "m")
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("MergeClients did not return after the merged client was evicted")
+	}
+	if s.ClientExists("a") {
+		t.Error("source client still registered")
+	}
+}
+
+func TestMergeClientsRelays(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("a")
+	if err := s.publish(t.Context(), "a", Event{Message: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MergeClients([]string{"a"}, "m"); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.RLock()
+	ch := s.clientChannels["m"].Channel
+	s.mu.RUnlock()
+	select {
+	case event := <-ch:
+		if event.Message != "hi" {
+			t.Fatalf("got %q", event.Message)
+		}
+	default:
+		t.Fatal("event was not relayed")
+	}
+}
@@ -0,0 +1,131 @@
+package lpoll
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetClientMetadata stores value under key for clientId. Metadata is kept in
+// memory only and is dropped with the client.
+func (s *Server) SetClientMetadata(clientId, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return ErrClientNotFound
+	}
+	if client.metadata == nil {
+		client.metadata = make(map[string]string)
+	}
+	client.metadata[key] = value
+	return nil
+}
+
+// GetClientMetadata returns the value stored under key for clientId.
+func (s *Server) GetClientMetadata(clientId, key string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return "", false, ErrClientNotFound
+	}
+	value, ok := client.metadata[key]
+	return value, ok, nil
+}
+
+// DeleteClientMetadata removes key from clientId's metadata.
+func (s *Server) DeleteClientMetadata(clientId, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return ErrClientNotFound
+	}
+	delete(client.metadata, key)
+	return nil
+}
+
+// clientMetadata returns a copy of all of clientId's metadata.
+func (s *Server) clientMetadata(clientId string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	md := make(map[string]string, len(client.metadata))
+	for k, v := range client.metadata {
+		md[k] = v
+	}
+	return md, nil
+}
+
+// ClientMetadataHandler serves GET /clients/:clientId/metadata with all of
+// the client's metadata.
+func ClientMetadataHandler(c *gin.Context) {
+	s := serverFrom(c)
+	clientId, err := s.adminClientID(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	md, err := s.clientMetadata(clientId)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, md)
+}
+
+// SetClientMetadataHandler serves PUT /clients/:clientId/metadata/:key with
+// a {"value": ...} body.
+func SetClientMetadataHandler(c *gin.Context) {
+	s := serverFrom(c)
+	clientId, err := s.adminClientID(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	var req struct {
+		Value string `json:"value"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.SetClientMetadata(clientId, c.Param("key"), req.Value); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteClientMetadataHandler serves DELETE /clients/:clientId/metadata/:key.
+func DeleteClientMetadataHandler(c *gin.Context) {
+	s := serverFrom(c)
+	clientId, err := s.adminClientID(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if err := s.DeleteClientMetadata(clientId, c.Param("key")); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// adminClientID returns the client the admin route c addresses. The ID is
+// always in the path, whatever ClientIDSource says, and is validated,
+// namespaced and resolved like extractClientID and the publish handler do.
+func (s *Server) adminClientID(c *gin.Context) (string, error) {
+	clientId := c.Param("clientId")
+	if err := validateClientID(s.options(), clientId); err != nil {
+		return "", err
+	}
+	if ns := c.GetString(namespaceKey); ns != "" {
+		clientId = ns + clientId
+	}
+	return s.resolveAlias(clientId), nil
+}
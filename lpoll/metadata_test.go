@@ -0,0 +1,65 @@
+package lpoll
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveAdmin runs one request against s's admin routes.
+func serveAdmin(s *Server, method, target, body string) *httptest.ResponseRecorder {
+	engine := gin.New()
+	s.RegisterAdminRoutes(engine)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	engine.ServeHTTP(w, r)
+	return w
+}
+
+func TestMetadataHandlersValidateClientID(t *testing.T) {
+	s := New(LpollOptions{
+		ClientIDValidator: func(clientId string) error {
+			if strings.HasPrefix(clientId, "bad") {
+				return errors.New("rejected")
+			}
+			return nil
+		},
+	})
+	s.EnsureClient("bad1")
+
+	for _, req := range []struct{ method, body string }{
+		{http.MethodGet, ""},
+		{http.MethodPut, `{"value":"v"}`},
+		{http.MethodDelete, ""},
+	} {
+		target := "/clients/bad1/metadata"
+		if req.method != http.MethodGet {
+			target += "/k"
+		}
+		if w := serveAdmin(s, req.method, target, req.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: got %d, want %d", req.method, target, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestMetadataHandlersResolveAlias(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+	if err := s.AddClientAlias("alias", "c1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := serveAdmin(s, http.MethodPut, "/clients/alias/metadata/plan", `{"value":"pro"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT: got %d: %s", w.Code, w.Body)
+	}
+	if value, ok, err := s.GetClientMetadata("c1", "plan"); err != nil || !ok || value != "pro" {
+		t.Fatalf("c1 metadata plan = %q, %v, %v", value, ok, err)
+	}
+}
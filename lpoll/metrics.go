@@ -0,0 +1,84 @@
+package lpoll
+
+import "time"
+
+// ServerMetrics is a snapshot of a server's counters and queues.
+type ServerMetrics struct {
+	At              time.Time `json:"at"`
+	Clients         int       `json:"clients"`
+	QueueDepth      int       `json:"queueDepth"`
+	QueueCapacity   int       `json:"queueCapacity"`
+	EventsPublished int64     `json:"eventsPublished"`
+	EventsDropped   int64     `json:"eventsDropped"`
+	PollTimeouts    int64     `json:"pollTimeouts"`
+}
+
+// metricsSubscription is one SubscribeMetrics channel and its ticker loop.
+type metricsSubscription struct {
+	ch   chan ServerMetrics
+	stop chan struct{}
+}
+
+// SubscribeMetrics returns a channel receiving a ServerMetrics snapshot
+// every interval. A snapshot is skipped if the previous one has not been
+// received yet. interval must be positive. Stop it with
+// UnsubscribeMetrics.
+func (s *Server) SubscribeMetrics(interval time.Duration) <-chan ServerMetrics {
+	ticker := time.NewTicker(interval)
+	sub := &metricsSubscription{
+		ch:   make(chan ServerMetrics, 1),
+		stop: make(chan struct{}),
+	}
+	s.metricsMu.Lock()
+	if s.metricsSubs == nil {
+		s.metricsSubs = make(map[<-chan ServerMetrics]*metricsSubscription)
+	}
+	s.metricsSubs[sub.ch] = sub
+	s.metricsMu.Unlock()
+
+	go func() {
+		defer ticker.Stop()
+		defer close(sub.ch)
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case sub.ch <- s.metrics():
+				default:
+				}
+			case <-sub.stop:
+				return
+			}
+		}
+	}()
+	return sub.ch
+}
+
+// UnsubscribeMetrics stops a SubscribeMetrics channel, which is then
+// closed. Unknown channels are ignored.
+func (s *Server) UnsubscribeMetrics(ch <-chan ServerMetrics) {
+	s.metricsMu.Lock()
+	sub, ok := s.metricsSubs[ch]
+	delete(s.metricsSubs, ch)
+	s.metricsMu.Unlock()
+	if ok {
+		close(sub.stop)
+	}
+}
+
+func (s *Server) metrics() ServerMetrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m := ServerMetrics{
+		At:              time.Now(),
+		Clients:         len(s.clientChannels),
+		EventsPublished: s.stats.eventsPublished.Value(),
+		EventsDropped:   s.stats.eventsDropped.Value(),
+		PollTimeouts:    s.stats.pollTimeouts.Value(),
+	}
+	for _, client := range s.clientChannels {
+		m.QueueDepth += len(client.Channel)
+		m.QueueCapacity += cap(client.Channel)
+	}
+	return m
+}
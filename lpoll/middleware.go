@@ -0,0 +1,34 @@
+package lpoll
+
+import "fmt"
+
+// Middleware wraps the final send of a publish. It may modify the event and
+// decides whether to pass it on by calling next.
+type Middleware func(clientId string, event Event, next func(Event))
+
+// RegisterMiddleware prepends mw to the publish chain, so the middleware
+// registered last runs first. The end of the chain is the channel send. An
+// event that a middleware does not pass on is rejected with
+// ErrEventRejected.
+func (s *Server) RegisterMiddleware(mw func(clientId string, event Event, next func(Event))) {
+	s.middlewareMu.Lock()
+	s.middleware = append([]Middleware{mw}, s.middleware...)
+	s.middlewareMu.Unlock()
+}
+
+// runMiddleware passes event through the chain and returns the error of
+// send, which ends it.
+func (s *Server) runMiddleware(clientId string, event Event, send func(Event) error) error {
+	s.middlewareMu.RLock()
+	chain := s.middleware
+	s.middlewareMu.RUnlock()
+
+	err := fmt.Errorf("%w: stopped by middleware", ErrEventRejected)
+	next := func(event Event) { err = send(event) }
+	for i := len(chain) - 1; i >= 0; i-- {
+		mw, inner := chain[i], next
+		next = func(event Event) { mw(clientId, event, inner) }
+	}
+	next(event)
+	return err
+}
@@ -0,0 +1,114 @@
+package lpoll
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// namespaceKey is the Gin context key NamespacedServer handlers store their
+// namespace under; extractClientID prefixes client IDs with it.
+const namespaceKey = "lpoll.namespace"
+
+// NamespacedServer is a view of a Server in which every client ID is
+// prefixed with the namespace and a colon. All namespaces share the
+// underlying server, but clients of one are never visible through another.
+type NamespacedServer struct {
+	server *Server
+	prefix string
+}
+
+// WithNamespace returns a view of s isolated to namespace ns. It panics if
+// ns contains a colon: namespace "a" with client "b:c" and namespace "a:b"
+// with client "c" would share the client "a:b:c".
+func (s *Server) WithNamespace(ns string) *NamespacedServer {
+	if strings.Contains(ns, ":") {
+		panic("lpoll: namespace " + strconv.Quote(ns) + " contains a colon")
+	}
+	return &NamespacedServer{server: s, prefix: ns + ":"}
+}
+
+func (n *NamespacedServer) id(clientId string) string {
+	return n.prefix + clientId
+}
+
+// ClientExists reports whether clientId is registered in the namespace.
+func (n *NamespacedServer) ClientExists(clientId string) bool {
+	return n.server.ClientExists(n.id(clientId))
+}
+
+// EnsureClient registers clientId in the namespace if it is not already.
+func (n *NamespacedServer) EnsureClient(clientId string) *ClientState {
+	return n.server.EnsureClient(n.id(clientId))
+}
+
+// PublishWithContext is Server.PublishWithContext within the namespace.
+func (n *NamespacedServer) PublishWithContext(ctx context.Context, clientId string, event Event) error {
+	return n.server.PublishWithContext(ctx, n.id(clientId), event)
+}
+
+// Subscribe is Server.Subscribe within the namespace. pattern is matched
+// against unprefixed IDs, and fn receives them unprefixed too.
+func (n *NamespacedServer) Subscribe(pattern string, fn func(clientId string, event Event)) func() {
+	return n.server.Subscribe(escapeGlob(n.prefix)+pattern, func(clientId string, event Event) {
+		fn(strings.TrimPrefix(clientId, n.prefix), event)
+	})
+}
+
+// Backfill is Server.Backfill within the namespace.
+func (n *NamespacedServer) Backfill(clientId string, events []Event) error {
+	return n.server.Backfill(n.id(clientId), events)
+}
+
+// Throttle is Server.Throttle within the namespace.
+func (n *NamespacedServer) Throttle(clientId string, rps float64) error {
+	return n.server.Throttle(n.id(clientId), rps)
+}
+
+// Inspect is Server.Inspect within the namespace.
+func (n *NamespacedServer) Inspect(clientId string) (*ClientSnapshot, error) {
+	snap, err := n.server.Inspect(n.id(clientId))
+	if err != nil {
+		return nil, err
+	}
+	snap.ClientID = clientId
+	return snap, nil
+}
+
+func (n *NamespacedServer) attach(c *gin.Context) {
+	c.Set(serverKey, n.server)
+	c.Set(namespaceKey, n.prefix)
+}
+
+// PollHandler is PollHandler for clients in the namespace.
+func (n *NamespacedServer) PollHandler(c *gin.Context) {
+	n.attach(c)
+	PollHandler(c)
+}
+
+// PublishHandler is PublishHandler for clients in the namespace.
+func (n *NamespacedServer) PublishHandler(c *gin.Context) {
+	n.attach(c)
+	PublishHandler(c)
+}
+
+// WebSocketHandler is WebSocketHandler for clients in the namespace.
+func (n *NamespacedServer) WebSocketHandler(c *gin.Context) {
+	n.attach(c)
+	WebSocketHandler(c)
+}
+
+// escapeGlob quotes the path.Match metacharacters in s.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
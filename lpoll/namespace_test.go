@@ -0,0 +1,25 @@
+package lpoll
+
+import "testing"
+
+func TestWithNamespaceRejectsColon(t *testing.T) {
+	s := New(LpollOptions{})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithNamespace accepted a namespace with a colon")
+		}
+	}()
+	s.WithNamespace("a:b")
+}
+
+func TestNamespacesIsolated(t *testing.T) {
+	s := New(LpollOptions{})
+	a, b := s.WithNamespace("a"), s.WithNamespace("b")
+	a.EnsureClient("c1")
+	if b.ClientExists("c1") {
+		t.Fatal("client of namespace a visible in namespace b")
+	}
+	if !s.ClientExists("a:c1") {
+		t.Fatal("namespaced client not registered under its prefixed ID")
+	}
+}
@@ -0,0 +1,132 @@
+package lpoll
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIDSource selects where handlers read the client ID from.
+type ClientIDSource int
+
+const (
+	// PathParam reads the ":clientId" path parameter.
+	PathParam ClientIDSource = iota
+	// QueryParam reads the "clientId" query parameter.
+	QueryParam
+	// Header reads the header named by LpollOptions.ClientIDHeaderName.
+	Header
+)
+
+// DefaultClientIDHeaderName is used when ClientIDSource is Header and no
+// ClientIDHeaderName is set.
+const DefaultClientIDHeaderName = "X-Client-ID"
+
+// LpollOptions configures a Server.
+type LpollOptions struct {
+	ClientIDSource     ClientIDSource
+	ClientIDHeaderName string
+	// DrainTimeout is how long DrainAndClose waits for buffered events to
+	// be consumed.
+	DrainTimeout time.Duration
+	// ClientIDValidator, if set, is called on every client ID a handler
+	// receives. An error rejects the request with 400.
+	ClientIDValidator func(string) error
+	// CacheableEventTypes lists the Event.Type values whose poll responses
+	// carry Cache-Control, ETag and Last-Modified headers.
+	CacheableEventTypes []string
+	// CacheMaxAge is the max-age sent for cacheable events.
+	CacheMaxAge time.Duration
+	// OnPollDelivery, if set, is called after PollHandler delivers an
+	// event, with the time elapsed since the event was published.
+	OnPollDelivery func(clientId string, event Event, pollDuration time.Duration)
+	// RequestTimeout caps the handling time of requests served by Listen,
+	// except polls and WebSockets, which are meant to stay open.
+	RequestTimeout time.Duration
+	// MaxClients caps the number of clients PollHandler and WebSocketHandler
+	// register. Further new polls get 503 and WebSockets are closed with
+	// StatusTryAgainLater. Zero means no limit.
+	MaxClients int
+	// OnMaxClientsReached, if set, is called with the ID of every client
+	// rejected by MaxClients, before the rejection is sent. It runs on the
+	// handler goroutine and must not block.
+	OnMaxClientsReached func(rejectedClientId string)
+	// RequireClientCert makes ListenTLS require a client certificate
+	// signed by one of the PEM certificates in ClientCACert.
+	RequireClientCert bool
+	ClientCACert      []byte
+	// EnforceClientCertCN rejects requests whose client certificate common
+	// name is not the client ID with 403.
+	EnforceClientCertCN bool
+	// CompressBufferedEvents gzips event messages while they wait in a
+	// client channel. They are decompressed on delivery.
+	CompressBufferedEvents bool
+	// OnPublishSuccess, if set, is called synchronously after PublishHandler
+	// or a WebSocket publish enqueues an event, with the client's queue
+	// depth after the send. It is not called for dropped events.
+	OnPublishSuccess func(clientId string, event Event, queueDepth int)
+	// ClientIDExistsChecker, if set, is asked whether an unknown client ID
+	// may be registered by PollHandler, WebSocketHandler or a lazy
+	// PublishHandler. false rejects the request with 404 and an error
+	// with 503.
+	ClientIDExistsChecker func(clientId string) (bool, error)
+	// ClientIDCacheTTL is how long a positive ClientIDExistsChecker answer
+	// is cached. Zero disables the cache.
+	ClientIDCacheTTL time.Duration
+	// LazyClientCreation makes PublishHandler register unknown clients
+	// instead of responding 404, so their events wait for the first poll.
+	// ClientIDExistsChecker and MaxClients still apply.
+	LazyClientCreation bool
+	// StrictMode rejects published events with an empty message, type or
+	// source with 422, see ValidateEvent.
+	StrictMode bool
+	// DefaultRetention is the retention policy of clients without one set
+	// by ConfigureRetention. The zero policy keeps stored events until their
+	// client is evicted.
+	DefaultRetention RetentionPolicy
+}
+
+// extractClientID reads the client ID from the place opts points to,
+// validates it and applies the request's namespace, if any.
+func extractClientID(c *gin.Context, opts LpollOptions) (string, error) {
+	var clientId string
+	switch opts.ClientIDSource {
+	case QueryParam:
+		clientId = c.Query("clientId")
+	case Header:
+		name := opts.ClientIDHeaderName
+		if name == "" {
+			name = DefaultClientIDHeaderName
+		}
+		clientId = c.GetHeader(name)
+	default:
+		clientId = c.Param("clientId")
+	}
+	if err := validateClientID(opts, clientId); err != nil {
+		return "", err
+	}
+	if opts.EnforceClientCertCN {
+		if err := checkClientCertCN(c.Request, clientId); err != nil {
+			return "", err
+		}
+	}
+	if ns := c.GetString(namespaceKey); ns != "" {
+		clientId = ns + clientId
+	}
+	return clientId, nil
+}
+
+// validateClientID rejects an empty clientId or one ClientIDValidator
+// refuses.
+func validateClientID(opts LpollOptions, clientId string) error {
+	if clientId == "" {
+		return fmt.Errorf("%w: clientId is required", ErrInvalidClientID)
+	}
+	if opts.ClientIDValidator != nil {
+		if err := opts.ClientIDValidator(clientId); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidClientID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package lpoll
+
+import "unicode/utf8"
+
+// SetBeforePublish installs hook to run on every event before it is
+// enqueued. A hook error rejects the publish with ErrEventRejected. Passing
+// nil removes the hook.
+func (s *Server) SetBeforePublish(hook func(Event) (Event, error)) {
+	s.beforePublishMu.Lock()
+	s.beforePublish = hook
+	s.beforePublishMu.Unlock()
+}
+
+func (s *Server) loadBeforePublish() func(Event) (Event, error) {
+	s.beforePublishMu.RLock()
+	defer s.beforePublishMu.RUnlock()
+	return s.beforePublish
+}
+
+// Pipeline composes stages into a single transform, applied in order, that
+// stops at the first error. The result can be passed to SetBeforePublish.
+func Pipeline(stages ...func(Event) (Event, error)) func(Event) (Event, error) {
+	return func(event Event) (Event, error) {
+		for _, stage := range stages {
+			var err error
+			if event, err = stage(event); err != nil {
+				return event, err
+			}
+		}
+		return event, nil
+	}
+}
+
+// TruncateMessage returns a stage that cuts Message to at most maxLen bytes
+// without splitting a UTF-8 character. A negative maxLen counts as zero.
+func TruncateMessage(maxLen int) func(Event) (Event, error) {
+	maxLen = max(maxLen, 0)
+	return func(event Event) (Event, error) {
+		if len(event.Message) <= maxLen {
+			return event, nil
+		}
+		cut := maxLen
+		for cut > 0 && !utf8.RuneStart(event.Message[cut]) {
+			cut--
+		}
+		event.Message = event.Message[:cut]
+		return event, nil
+	}
+}
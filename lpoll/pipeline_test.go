@@ -0,0 +1,25 @@
+package lpoll
+
+import "testing"
+
+func TestTruncateMessage(t *testing.T) {
+	for _, tc := range []struct {
+		maxLen    int
+		message   string
+		truncated string
+	}{
+		{5, "hello world", "hello"},
+		{20, "hello", "hello"},
+		{2, "héllo", "h"},
+		{0, "hello", ""},
+		{-1, "hello", ""},
+	} {
+		event, err := TruncateMessage(tc.maxLen)(Event{Message: tc.message})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Message != tc.truncated {
+			t.Errorf("TruncateMessage(%d) on %q = %q, want %q", tc.maxLen, tc.message, event.Message, tc.truncated)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package lpoll
+
+import "sync"
+
+// presenceBuffer is the capacity of each SubscribePresence channel.
+const presenceBuffer = 16
+
+// presence tracks the polls in flight for one client and who to tell when
+// the client goes online or offline.
+type presence struct {
+	polls int
+	subs  []chan bool
+}
+
+// SubscribePresence returns a channel receiving true when clientId starts
+// polling and false when its last poll in flight ends. The client need not
+// be registered yet. Updates are dropped while the channel is full. cancel
+// removes the subscription and closes the channel.
+func (s *Server) SubscribePresence(clientId string) (<-chan bool, func()) {
+	ch := make(chan bool, presenceBuffer)
+
+	s.presenceMu.Lock()
+	if s.presence == nil {
+		s.presence = make(map[string]*presence)
+	}
+	p := s.presence[clientId]
+	if p == nil {
+		p = &presence{}
+		s.presence[clientId] = p
+	}
+	p.subs = append(p.subs, ch)
+	s.presenceMu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			s.presenceMu.Lock()
+			defer s.presenceMu.Unlock()
+			for i, other := range p.subs {
+				if other == ch {
+					p.subs = append(p.subs[:i:i], p.subs[i+1:]...)
+					break
+				}
+			}
+			s.prunePresence(clientId, p)
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// setPolling records that a poll for clientId started (true) or ended
+// (false), notifying the subscribers when the client goes online or
+// offline.
+func (s *Server) setPolling(clientId string, polling bool) {
+	s.presenceMu.Lock()
+	defer s.presenceMu.Unlock()
+	p := s.presence[clientId]
+	if p == nil {
+		if !polling {
+			return
+		}
+		if s.presence == nil {
+			s.presence = make(map[string]*presence)
+		}
+		p = &presence{}
+		s.presence[clientId] = p
+	}
+
+	if polling {
+		p.polls++
+		if p.polls > 1 {
+			return
+		}
+	} else {
+		p.polls--
+		if p.polls > 0 {
+			return
+		}
+		s.prunePresence(clientId, p)
+	}
+	for _, ch := range p.subs {
+		select {
+		case ch <- polling:
+		default:
+		}
+	}
+}
+
+// prunePresence drops p once nothing refers to it. presenceMu must be held.
+func (s *Server) prunePresence(clientId string, p *presence) {
+	if p.polls == 0 && len(p.subs) == 0 {
+		delete(s.presence, clientId)
+	}
+}
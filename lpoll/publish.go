@@ -0,0 +1,89 @@
+package lpoll
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// PublishWithContext enqueues event for clientId, blocking while the
+// client's channel is full until there is room or ctx is done. It returns
+// ctx.Err() on cancellation, ErrClientNotFound if the client does not exist,
+// ErrClientEvicted if it is evicted while waiting, and ErrChannelFull if the
+// client has not polled since it was restored and so has no channel yet.
+func (s *Server) PublishWithContext(ctx context.Context, clientId string, event Event) (err error) {
+	event, err = s.preparePublish(ctx, clientId, event)
+	if err != nil {
+		return err
+	}
+	return s.runMiddleware(clientId, event, func(event Event) error {
+		return s.sendWait(ctx, clientId, event)
+	})
+}
+
+// sendWait is the blocking counterpart of send.
+func (s *Server) sendWait(ctx context.Context, clientId string, event Event) error {
+	client, err := s.sendBlocking(ctx, clientId, s.compressEvent(event))
+	if err != nil {
+		return err
+	}
+	atomic.AddUint64(&client.TotalPublished, 1)
+	s.stats.eventsPublished.Add(1)
+	s.notifyTaps(clientId, event)
+	return nil
+}
+
+// sendBlocking sends event to clientId's channel as it is, blocking while
+// the channel is full until there is room or ctx is done. The event is not
+// compressed or counted; callers do that. It returns ErrClientNotFound if
+// the client does not exist, ErrClientEvicted if it is evicted while
+// waiting, and ErrChannelFull if it has no channel yet.
+func (s *Server) sendBlocking(ctx context.Context, clientId string, event Event) (*ClientState, error) {
+	for attempt := 0; ; attempt++ {
+		// Every send happens under the read lock, so the channel cannot be
+		// closed or replaced mid-send.
+		s.mu.RLock()
+		client, ok := s.clientChannels[clientId]
+		if !ok {
+			s.mu.RUnlock()
+			if attempt > 0 {
+				return nil, ErrClientEvicted
+			}
+			return nil, ErrClientNotFound
+		}
+		if client.Channel == nil {
+			s.mu.RUnlock()
+			return client, ErrChannelFull
+		}
+		if deferToStore(client, event) {
+			s.mu.RUnlock()
+			return client, nil
+		}
+		select {
+		case client.Channel <- event:
+			// Pass on the wakeup in case other publishers are waiting.
+			if len(client.Channel) < cap(client.Channel) {
+				signalRoom(client)
+			}
+			s.mu.RUnlock()
+			return client, nil
+		default:
+		}
+		room, swapped := client.room, client.swapped
+		s.mu.RUnlock()
+
+		if room == nil || swapped == nil {
+			// Set the signals up and try again, as a receive may have
+			// happened before they existed.
+			s.watchChannel(client)
+			continue
+		}
+		// Wait without the lock, since pollers need the write lock to
+		// connect.
+		select {
+		case <-room:
+		case <-swapped:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
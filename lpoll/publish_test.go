@@ -0,0 +1,82 @@
+package lpoll
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPublishWithContextWaitsForRoom(t *testing.T) {
+	s := New(LpollOptions{})
+	client := s.EnsureClient("c1")
+	if err := s.send("c1", Event{Message: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	const publishers = 3
+	var wg sync.WaitGroup
+	errs := make(chan error, publishers)
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.PublishWithContext(context.Background(), "c1", Event{Message: "waiting"})
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// Taking events one by one lets each waiting publisher in.
+	for i := 0; i <= publishers; i++ {
+		select {
+		case <-client.Channel:
+			s.received(client)
+		case <-time.After(time.Second):
+			t.Fatalf("publisher %d did not send after an event was taken", i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPublishWithContextClientEvicted(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+	if err := s.send("c1", Event{Message: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	published := make(chan error, 1)
+	go func() { published <- s.PublishWithContext(context.Background(), "c1", Event{Message: "waiting"}) }()
+	time.Sleep(20 * time.Millisecond)
+	evict(s, "c1")
+
+	select {
+	case err := <-published:
+		if !errors.Is(err, ErrClientEvicted) {
+			t.Fatalf("got %v, want ErrClientEvicted", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publisher kept waiting on an evicted client")
+	}
+}
+
+func TestPublishWithContextCancelled(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+	if err := s.send("c1", Event{Message: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.PublishWithContext(ctx, "c1", Event{Message: "waiting"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,85 @@
+package lpoll
+
+import "log"
+
+// PushNotification is the payload handed to a PushProvider.
+type PushNotification struct {
+	ClientID string `json:"clientId"`
+	Event    Event  `json:"event"`
+}
+
+// PushProvider delivers notifications out of band, e.g. through APNs or FCM.
+type PushProvider interface {
+	Send(deviceToken string, n PushNotification) error
+}
+
+// SetPushProvider installs p as the fallback for events that cannot be
+// enqueued because the client is offline or its channel is full. Only
+// clients with a push token are notified. Passing nil disables the fallback.
+func (s *Server) SetPushProvider(p PushProvider) {
+	s.pushProviderMu.Lock()
+	s.pushProvider = p
+	s.pushProviderMu.Unlock()
+}
+
+// SetPushToken registers the device token used to notify clientId.
+func (s *Server) SetPushToken(clientId, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return ErrClientNotFound
+	}
+	client.PushToken = token
+	return nil
+}
+
+// Notify sends n to clientId through the push provider right away, whether
+// or not the client is polling. n.ClientID defaults to clientId. It returns
+// ErrNoPushProvider, ErrClientNotFound or ErrNoPushToken if the notification
+// cannot be addressed, and otherwise the provider's error.
+func (s *Server) Notify(clientId string, n PushNotification) error {
+	s.pushProviderMu.RLock()
+	p := s.pushProvider
+	s.pushProviderMu.RUnlock()
+	if p == nil {
+		return ErrNoPushProvider
+	}
+
+	s.mu.RLock()
+	client, ok := s.clientChannels[clientId]
+	var token string
+	if ok {
+		token = client.PushToken
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return ErrClientNotFound
+	}
+	if token == "" {
+		return ErrNoPushToken
+	}
+
+	if n.ClientID == "" {
+		n.ClientID = clientId
+	}
+	// Send outside the lock; providers make network calls.
+	return p.Send(token, n)
+}
+
+// sendPush notifies the client through the push provider, if both a
+// provider and a push token are set.
+func (s *Server) sendPush(clientId, token string, event Event) {
+	s.pushProviderMu.RLock()
+	p := s.pushProvider
+	s.pushProviderMu.RUnlock()
+	if p == nil || token == "" {
+		return
+	}
+
+	if err := p.Send(token, PushNotification{ClientID: clientId, Event: event}); err != nil {
+		log.Printf("Push notification failed for client %s: %v", clientId, err)
+		return
+	}
+	log.Printf("Sent push notification to client: %s", clientId)
+}
@@ -0,0 +1,60 @@
+package lpoll
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// recordingPushProvider records the notifications it is asked to send.
+type recordingPushProvider struct {
+	mu     sync.Mutex
+	tokens []string
+	sent   []PushNotification
+	err    error
+}
+
+func (p *recordingPushProvider) Send(deviceToken string, n PushNotification) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokens = append(p.tokens, deviceToken)
+	p.sent = append(p.sent, n)
+	return p.err
+}
+
+func TestNotify(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+	s.EnsureClient("c2")
+
+	if err := s.Notify("c1", PushNotification{}); !errors.Is(err, ErrNoPushProvider) {
+		t.Fatalf("without provider: got %v, want ErrNoPushProvider", err)
+	}
+
+	p := &recordingPushProvider{}
+	s.SetPushProvider(p)
+	if err := s.SetPushToken("c1", "token-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Notify("missing", PushNotification{}); !errors.Is(err, ErrClientNotFound) {
+		t.Fatalf("unknown client: got %v, want ErrClientNotFound", err)
+	}
+	if err := s.Notify("c2", PushNotification{}); !errors.Is(err, ErrNoPushToken) {
+		t.Fatalf("client without token: got %v, want ErrNoPushToken", err)
+	}
+
+	if err := s.Notify("c1", PushNotification{Event: Event{Message: "hi"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.sent) != 1 || p.tokens[0] != "token-1" || p.sent[0].ClientID != "c1" || p.sent[0].Event.Message != "hi" {
+		t.Fatalf("provider got %v %+v", p.tokens, p.sent)
+	}
+	if n := len(s.clientChannels["c1"].Channel); n != 0 {
+		t.Fatal("Notify enqueued the event")
+	}
+
+	p.err = errors.New("provider down")
+	if err := s.Notify("c1", PushNotification{}); err != p.err {
+		t.Fatalf("got %v, want the provider error", err)
+	}
+}
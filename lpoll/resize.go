@@ -0,0 +1,112 @@
+package lpoll
+
+import (
+	"fmt"
+	"log"
+)
+
+// ResizeClientChannel replaces the client's channel with one of capacity
+// newSize, moving the buffered events over. It returns an error wrapping
+// ErrBufferFull, leaving the channel alone, if more than newSize events are
+// buffered. Polls and WebSockets waiting on the old channel are woken and
+// move to the new one; ForwardTo and MergeClients relays keep reading the
+// old one and must be set up again.
+func (s *Server) ResizeClientChannel(clientId string, newSize int) error {
+	if newSize < 1 {
+		return fmt.Errorf("lpoll: invalid channel size %d", newSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return ErrClientNotFound
+	}
+	if client.Channel == nil {
+		return fmt.Errorf("%w: client has not connected yet", ErrClientNotFound)
+	}
+	if cap(client.Channel) == newSize {
+		return nil
+	}
+	if n := len(client.Channel); n > newSize {
+		return fmt.Errorf("%w: %d events buffered", ErrBufferFull, n)
+	}
+
+	ch := make(chan Event, newSize)
+	for moved := false; !moved; {
+		select {
+		case event := <-client.Channel:
+			ch <- event
+		default:
+			moved = true
+		}
+	}
+	client.Channel = ch
+	signalSwap(client)
+	log.Printf("Resized channel of client %s to %d", clientId, newSize)
+	return nil
+}
+
+// clientChannel reads client.Channel under the lock, since
+// ResizeClientChannel may swap it.
+func (s *Server) clientChannel(client *ClientState) chan Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return client.Channel
+}
+
+// watchChannel returns client.Channel along with a channel that is closed
+// when the channel is replaced or closed, and sets up the room signal.
+func (s *Server) watchChannel(client *ClientState) (chan Event, <-chan struct{}) {
+	s.mu.RLock()
+	ch, swapped := client.Channel, client.swapped
+	ready := swapped != nil && client.room != nil
+	s.mu.RUnlock()
+	if ready {
+		return ch, swapped
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if client.swapped == nil {
+		client.swapped = make(chan struct{})
+	}
+	if client.room == nil {
+		client.room = make(chan struct{}, 1)
+	}
+	return client.Channel, client.swapped
+}
+
+// signalSwap wakes everyone waiting on client's channel after it was
+// replaced or closed. Call it under the write lock.
+func signalSwap(client *ClientState) {
+	if client.swapped != nil {
+		close(client.swapped)
+		client.swapped = nil
+	}
+}
+
+// closeChannel closes client's channel on eviction. Call it under the
+// write lock.
+func closeChannel(client *ClientState) {
+	if client.Channel != nil {
+		close(client.Channel)
+	}
+	signalSwap(client)
+}
+
+// signalRoom wakes a publisher waiting for room in client's channel. Call
+// it under the read lock.
+func signalRoom(client *ClientState) {
+	select {
+	case client.room <- struct{}{}:
+	default:
+	}
+}
+
+// received tells publishers waiting on client that an event was taken from
+// its channel.
+func (s *Server) received(client *ClientState) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	signalRoom(client)
+}
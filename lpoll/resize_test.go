@@ -0,0 +1,180 @@
+package lpoll
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+func TestPublishWithContextDuringResize(t *testing.T) {
+	s := New(LpollOptions{})
+	client := s.EnsureClient("c1")
+	if err := s.send("c1", Event{Message: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	published := make(chan error, 1)
+	go func() { published <- s.PublishWithContext(ctx, "c1", Event{Message: "second"}) }()
+
+	// Let the publish start waiting on the full channel.
+	time.Sleep(20 * time.Millisecond)
+	if err := s.ResizeClientChannel("c1", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-published; err != nil {
+		t.Fatal(err)
+	}
+
+	ch := s.clientChannel(client)
+	if n := len(ch); n != 2 {
+		t.Fatalf("resized channel holds %d events, want 2", n)
+	}
+	for _, want := range []string{"first", "second"} {
+		if got := (<-ch).Message; got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestPublishWithContextNoLossUnderResize(t *testing.T) {
+	const events = 100
+	s := New(LpollOptions{})
+	client := s.EnsureClient("c1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	published := make(chan error, 1)
+	go func() {
+		for i := 0; i < events; i++ {
+			if err := s.PublishWithContext(ctx, "c1", Event{Message: "hi"}); err != nil {
+				published <- err
+				return
+			}
+		}
+		published <- nil
+	}()
+	stop := make(chan struct{})
+	resized := make(chan struct{})
+	go func() {
+		defer close(resized)
+		for size := 1; ; size = size%4 + 1 {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			s.ResizeClientChannel("c1", size)
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-resized
+	}()
+
+	// Only ever read the current channel; an event sent to a replaced one
+	// is lost.
+	deadline := time.Now().Add(5 * time.Second)
+	for received := 0; received < events; {
+		select {
+		case <-s.clientChannel(client):
+			received++
+		default:
+			if time.Now().After(deadline) {
+				t.Fatalf("received %d of %d events", received, events)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if err := <-published; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResizeClientChannelTooSmall(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("c1")
+	if err := s.ResizeClientChannel("c1", 4); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.send("c1", Event{Message: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.ResizeClientChannel("c1", 2); err == nil {
+		t.Fatal("shrinking below the buffered events succeeded")
+	}
+}
+
+func TestPollInFlightDuringResize(t *testing.T) {
+	s := New(LpollOptions{})
+	s.SetGlobalTimeout(3 * time.Second)
+	s.EnsureClient("c1")
+
+	polled := make(chan string, 1)
+	go func() { polled <- pollMessage(t, s, "/poll/c1") }()
+	// Let the poll start waiting on the old channel.
+	time.Sleep(50 * time.Millisecond)
+	if err := s.ResizeClientChannel("c1", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.send("c1", Event{Message: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-polled:
+		if got != "hi" {
+			t.Fatalf("got %q, want \"hi\"", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("poll stayed on the replaced channel")
+	}
+}
+
+func TestWebSocketDuringResize(t *testing.T) {
+	s := New(LpollOptions{})
+	engine := gin.New()
+	s.RegisterRoutes(engine)
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/c1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+	for i := 0; !s.ClientExists("c1"); i++ {
+		if i == 100 {
+			t.Fatal("WebSocket did not register the client")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if err := s.ResizeClientChannel("c1", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.send("c1", Event{Message: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	readCtx, cancelRead := context.WithTimeout(ctx, time.Second)
+	defer cancelRead()
+	var event Event
+	if err := wsjson.Read(readCtx, conn, &event); err != nil {
+		t.Fatalf("WebSocket stayed on the replaced channel: %v", err)
+	}
+	if event.Message != "hi" {
+		t.Fatalf("got %q, want \"hi\"", event.Message)
+	}
+}
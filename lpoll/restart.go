@@ -0,0 +1,56 @@
+package lpoll
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// GracefulRestart replaces the server's options without dropping clients.
+// Polls in flight finish with the options they started with; new requests
+// use newOpts. Options that are only read when the server starts listening
+// cannot change and are reported as an error, as are invalid values.
+func (s *Server) GracefulRestart(newOpts LpollOptions) error {
+	if err := validateOptions(newOpts); err != nil {
+		return err
+	}
+
+	s.optsMu.Lock()
+	defer s.optsMu.Unlock()
+	old := s.opts
+	switch {
+	case newOpts.ClientIDSource != old.ClientIDSource:
+		return errors.New("lpoll: ClientIDSource cannot change, the routes are already registered")
+	case newOpts.RequestTimeout != old.RequestTimeout:
+		return errors.New("lpoll: RequestTimeout cannot change, Listen already wraps the handler with it")
+	case newOpts.RequireClientCert != old.RequireClientCert,
+		!bytes.Equal(newOpts.ClientCACert, old.ClientCACert):
+		return errors.New("lpoll: client certificate options cannot change, the TLS config is already in use")
+	}
+	s.opts = newOpts
+	return nil
+}
+
+func validateOptions(opts LpollOptions) error {
+	switch {
+	case opts.MaxClients < 0:
+		return fmt.Errorf("lpoll: MaxClients must not be negative, got %d", opts.MaxClients)
+	case opts.DrainTimeout < 0, opts.CacheMaxAge < 0, opts.RequestTimeout < 0, opts.ClientIDCacheTTL < 0:
+		return errors.New("lpoll: durations must not be negative")
+	}
+	return nil
+}
+
+// LazyClientCreation turns LpollOptions.LazyClientCreation on or off.
+func (s *Server) LazyClientCreation(enabled bool) {
+	s.optsMu.Lock()
+	s.opts.LazyClientCreation = enabled
+	s.optsMu.Unlock()
+}
+
+// options returns a copy of the current options.
+func (s *Server) options() LpollOptions {
+	s.optsMu.RLock()
+	defer s.optsMu.RUnlock()
+	return s.opts
+}
@@ -0,0 +1,199 @@
+package lpoll
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// RetentionPolicy bounds the stored history of a client. Zero fields are
+// not enforced. Only the event store is trimmed, never the live channel.
+type RetentionPolicy struct {
+	MaxEvents int
+	MaxAge    time.Duration
+	// MaxBytes limits the total size of the stored messages.
+	MaxBytes int64
+}
+
+// retainedEvent is what a retention policy needs to know of a stored event.
+type retainedEvent struct {
+	seq       uint64
+	size      int64
+	published time.Time
+}
+
+// retainedEvents indexes a client's stored events, oldest first, so its
+// policy can be enforced on every publish without loading its history. It
+// is built from the store on the first publish and rebuilt by every
+// ApplyRetentionPolicies.
+type retainedEvents struct {
+	mu     sync.Mutex
+	loaded bool
+	events []retainedEvent
+	bytes  int64
+}
+
+// ConfigureRetention sets the retention policy of clientId's stored
+// events, enforced after every publish to the client and by
+// ApplyRetentionPolicies. It overrides LpollOptions.DefaultRetention; the
+// zero policy removes it.
+func (s *Server) ConfigureRetention(clientId string, policy RetentionPolicy) {
+	s.retentionMu.Lock()
+	defer s.retentionMu.Unlock()
+	delete(s.retained, clientId)
+	if policy == (RetentionPolicy{}) {
+		delete(s.retention, clientId)
+		return
+	}
+	if s.retention == nil {
+		s.retention = make(map[string]RetentionPolicy)
+	}
+	s.retention[clientId] = policy
+}
+
+// ApplyRetentionPolicies trims the stored events of every client with a
+// retention policy, its own or LpollOptions.DefaultRetention. It runs
+// synchronously and at the end of every cleanup pass.
+func (s *Server) ApplyRetentionPolicies() {
+	if s.eventStore == nil {
+		return
+	}
+	clientIds := make(map[string]bool)
+	s.retentionMu.RLock()
+	for clientId := range s.retention {
+		clientIds[clientId] = true
+	}
+	s.retentionMu.RUnlock()
+	if s.options().DefaultRetention != (RetentionPolicy{}) {
+		s.mu.RLock()
+		for clientId := range s.clientChannels {
+			clientIds[clientId] = true
+		}
+		s.mu.RUnlock()
+	}
+
+	for clientId := range clientIds {
+		s.applyRetention(clientId, nil)
+	}
+}
+
+// retentionIndex returns clientId's policy and the index it is enforced
+// on, or false if the client has no policy.
+func (s *Server) retentionIndex(clientId string) (RetentionPolicy, *retainedEvents, bool) {
+	s.retentionMu.Lock()
+	defer s.retentionMu.Unlock()
+	policy, ok := s.retention[clientId]
+	if !ok {
+		policy = s.options().DefaultRetention
+		if policy == (RetentionPolicy{}) {
+			return policy, nil, false
+		}
+	}
+	idx, ok := s.retained[clientId]
+	if !ok {
+		if s.retained == nil {
+			s.retained = make(map[string]*retainedEvents)
+		}
+		idx = &retainedEvents{}
+		s.retained[clientId] = idx
+	}
+	return policy, idx, true
+}
+
+// applyRetention trims the oldest of clientId's stored events until the
+// rest fit its policy. published is the event just appended, which is
+// added to the index; with nil the index is rebuilt from the store.
+func (s *Server) applyRetention(clientId string, published *Event) {
+	policy, idx, ok := s.retentionIndex(clientId)
+	if !ok {
+		return
+	}
+
+	idx.mu.Lock()
+	if published == nil || !idx.loaded {
+		events, err := s.eventStore.Load(clientId, 0)
+		if err != nil {
+			idx.mu.Unlock()
+			log.Printf("Failed to load stored events of client %s for retention: %v", clientId, err)
+			return
+		}
+		idx.events, idx.bytes = idx.events[:0], 0
+		for _, event := range events {
+			idx.add(event)
+		}
+		idx.loaded = true
+	}
+	if published != nil {
+		idx.add(*published)
+	}
+	beforeSeq := idx.trim(policy, time.Now())
+	idx.mu.Unlock()
+
+	if beforeSeq == 0 {
+		return
+	}
+	if err := s.eventStore.Trim(clientId, beforeSeq); err != nil {
+		log.Printf("Failed to trim stored events of client %s: %v", clientId, err)
+	}
+}
+
+// add inserts event in Seq order, unless it is indexed already. Concurrent
+// publishes may arrive slightly out of order.
+func (idx *retainedEvents) add(event Event) {
+	published := event.PublishedAt
+	if published.IsZero() {
+		published = event.Time
+	}
+	i := len(idx.events)
+	for i > 0 && idx.events[i-1].seq >= event.Seq {
+		if idx.events[i-1].seq == event.Seq {
+			return
+		}
+		i--
+	}
+	idx.events = append(idx.events, retainedEvent{})
+	copy(idx.events[i+1:], idx.events[i:])
+	idx.events[i] = retainedEvent{seq: event.Seq, size: int64(len(event.Message)), published: published}
+	idx.bytes += int64(len(event.Message))
+}
+
+// trim drops the oldest events until the rest fit policy and returns the
+// Seq to trim the store before, or 0 if nothing was dropped.
+func (idx *retainedEvents) trim(policy RetentionPolicy, now time.Time) (beforeSeq uint64) {
+	keep := 0
+	for ; keep < len(idx.events); keep++ {
+		event := idx.events[keep]
+		remaining := len(idx.events) - keep
+		if (policy.MaxEvents <= 0 || remaining <= policy.MaxEvents) &&
+			(policy.MaxAge <= 0 || now.Sub(event.published) <= policy.MaxAge) &&
+			(policy.MaxBytes <= 0 || idx.bytes <= policy.MaxBytes) {
+			break
+		}
+		idx.bytes -= event.size
+	}
+	if keep == 0 {
+		return 0
+	}
+	beforeSeq = idx.events[keep-1].seq + 1
+	if keep < len(idx.events) {
+		beforeSeq = idx.events[keep].seq
+	}
+	idx.events = append(idx.events[:0], idx.events[keep:]...)
+	return beforeSeq
+}
+
+// forgetClient drops the stored state and events of a client that was
+// evicted for good, while lastSeq was seq. A client registered anew under
+// the same ID starts after them, so they could never be delivered.
+func (s *Server) forgetClient(clientId string, seq uint64) {
+	s.deleteClientState(clientId)
+	s.retentionMu.Lock()
+	delete(s.retained, clientId)
+	s.retentionMu.Unlock()
+	if s.eventStore == nil {
+		return
+	}
+	if err := s.eventStore.Trim(clientId, seq+1); err != nil {
+		log.Printf("Failed to trim stored events of evicted client %s: %v", clientId, err)
+	}
+}
@@ -0,0 +1,108 @@
+package lpoll
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// publishStored publishes n events to clientId for the store, taking each
+// one out of the channel so the next fits.
+func publishStored(t *testing.T, s *Server, client *ClientState, clientId string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := s.publish(t.Context(), clientId, Event{Message: "event " + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-client.Channel:
+			s.received(client)
+		default:
+		}
+	}
+}
+
+func storedMessages(st *memStore, clientId string) []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var messages []string
+	for _, event := range st.events[clientId] {
+		messages = append(messages, event.Message)
+	}
+	return messages
+}
+
+func TestRetentionOnPublishDoesNotLoadHistory(t *testing.T) {
+	s := New(LpollOptions{})
+	var loads atomic.Int32
+	st := &memStore{onLoad: func() { loads.Add(1) }}
+	s.SetEventStore(st)
+	s.ConfigureRetention("a", RetentionPolicy{MaxEvents: 3})
+	client := s.EnsureClient("a")
+	loaded := loads.Load()
+
+	publishStored(t, s, client, "a", 10)
+	// The index is built from the store once, on the first publish.
+	if n := loads.Load() - loaded; n > 1 {
+		t.Fatalf("10 publishes loaded the store %d times", n)
+	}
+	got := storedMessages(st, "a")
+	if len(got) != 3 || got[0] != "event 7" || got[2] != "event 9" {
+		t.Fatalf("stored %q, want the last 3 events", got)
+	}
+}
+
+func TestRetentionIndexBuiltFromStore(t *testing.T) {
+	s := New(LpollOptions{})
+	st := &memStore{}
+	s.SetEventStore(st)
+	for i := 1; i <= 4; i++ {
+		st.Append("a", Event{Message: "old " + strconv.Itoa(i), Seq: uint64(i)})
+	}
+	lastSeq.Store(max(lastSeq.Load(), 4))
+	s.ConfigureRetention("a", RetentionPolicy{MaxEvents: 2})
+	client := s.EnsureClient("a")
+
+	publishStored(t, s, client, "a", 1)
+	got := storedMessages(st, "a")
+	if len(got) != 2 || got[0] != "old 4" || got[1] != "event 0" {
+		t.Fatalf("stored %q, want the newest old event and the published one", got)
+	}
+
+	// The sweep rebuilds the index and enforces a tightened policy.
+	s.ConfigureRetention("a", RetentionPolicy{MaxEvents: 1})
+	s.ApplyRetentionPolicies()
+	if got := storedMessages(st, "a"); len(got) != 1 || got[0] != "event 0" {
+		t.Fatalf("stored %q after the sweep", got)
+	}
+}
+
+func TestDefaultRetention(t *testing.T) {
+	s := New(LpollOptions{DefaultRetention: RetentionPolicy{MaxEvents: 2}})
+	st := &memStore{}
+	s.SetEventStore(st)
+	client := s.EnsureClient("a")
+
+	publishStored(t, s, client, "a", 5)
+	if got := storedMessages(st, "a"); len(got) != 2 {
+		t.Fatalf("stored %q, want 2 events", got)
+	}
+}
+
+func TestCleanupTrimsEvictedClientEvents(t *testing.T) {
+	s := New(LpollOptions{})
+	st := &memStore{}
+	s.SetEventStore(st)
+	client := s.EnsureClient("a")
+	publishStored(t, s, client, "a", 3)
+
+	s.mu.Lock()
+	client.LastSeen = client.LastSeen.Add(-2 * s.clientTimeout)
+	s.mu.Unlock()
+	if evicted := s.CleanupNow(); evicted != 1 {
+		t.Fatalf("evicted %d clients", evicted)
+	}
+	if got := storedMessages(st, "a"); len(got) != 0 {
+		t.Fatalf("stored %q after eviction", got)
+	}
+}
@@ -0,0 +1,68 @@
+package lpoll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// roundRobinGroup is a set of workers sharing events, see RoundRobinGroup.
+type roundRobinGroup struct {
+	mu        sync.Mutex
+	clientIds []string
+	cursor    uint64
+}
+
+// RoundRobinGroup creates or replaces the group name, whose events
+// PublishRoundRobin delivers to one of clientIds at a time, in turn.
+func (s *Server) RoundRobinGroup(name string, clientIds []string) {
+	g := &roundRobinGroup{clientIds: append([]string(nil), clientIds...)}
+	s.groupsMu.Lock()
+	defer s.groupsMu.Unlock()
+	if s.groups == nil {
+		s.groups = make(map[string]*roundRobinGroup)
+	}
+	s.groups[name] = g
+}
+
+// PublishRoundRobin publishes event to the next worker of group name,
+// moving on to the following one while a worker's channel is full or it is
+// not registered. It returns the worker that got the event, or an error
+// wrapping ErrChannelFull if none could take it.
+func (s *Server) PublishRoundRobin(ctx context.Context, name string, event Event) (clientId string, err error) {
+	s.groupsMu.RLock()
+	g, ok := s.groups[name]
+	s.groupsMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("lpoll: unknown group %q", name)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n := uint64(len(g.clientIds))
+	start := g.cursor
+	g.cursor++
+	for i := uint64(0); i < n; i++ {
+		clientId := g.clientIds[(start+i)%n]
+		// Skip full workers up front, so the publish hooks, the event
+		// store and push notifications only see the chosen one.
+		if !s.hasRoom(clientId) {
+			continue
+		}
+		err := s.publish(ctx, clientId, event)
+		if errors.Is(err, ErrChannelFull) || errors.Is(err, ErrClientNotFound) {
+			continue
+		}
+		return clientId, err
+	}
+	return "", fmt.Errorf("%w: all %d workers of group %q", ErrChannelFull, n, name)
+}
+
+// hasRoom reports whether clientId is registered with room in its channel.
+func (s *Server) hasRoom(clientId string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.clientChannels[clientId]
+	return ok && client.Channel != nil && len(client.Channel) < cap(client.Channel)
+}
@@ -0,0 +1,63 @@
+package lpoll
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// RegisterSchema compiles jsonSchema and registers it under schemaName.
+// Events published with a matching Schema must carry a Message that is a
+// JSON document valid against it. Registering the same name again replaces
+// the previous schema.
+func (s *Server) RegisterSchema(schemaName string, jsonSchema []byte) error {
+	if schemaName == "" {
+		return errors.New("schema name is required")
+	}
+
+	resource := "mem:///" + url.PathEscape(schemaName)
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(resource, bytes.NewReader(jsonSchema)); err != nil {
+		return fmt.Errorf("schema %q: %w", schemaName, err)
+	}
+	schema, err := compiler.Compile(resource)
+	if err != nil {
+		return fmt.Errorf("schema %q: %w", schemaName, err)
+	}
+
+	s.schemasMu.Lock()
+	s.schemas[schemaName] = schema
+	s.schemasMu.Unlock()
+	return nil
+}
+
+// validateSchema checks the event's Message against its registered schema.
+// Events without a Schema are always valid.
+func (s *Server) validateSchema(event Event) error {
+	if event.Schema == "" {
+		return nil
+	}
+
+	s.schemasMu.RLock()
+	schema, ok := s.schemas[event.Schema]
+	s.schemasMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: unknown schema %q", ErrSchemaValidation, event.Schema)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(event.Message))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("%w: message is not valid JSON: %v", ErrSchemaValidation, err)
+	}
+	if err := schema.Validate(doc); err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaValidation, err)
+	}
+	return nil
+}
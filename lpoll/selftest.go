@@ -0,0 +1,74 @@
+package lpoll
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// selfTestTimeout bounds a SelfTest run.
+const selfTestTimeout = 2 * time.Second
+
+// SelfTest routes a sentinel event through the channel of a temporary
+// client and checks it comes back unchanged, for use in readiness probes.
+// Like Benchmark it does the channel send directly, so the event skips the
+// publish hooks, middleware, stats and state store, and the temporary client
+// is never announced. It is removed before SelfTest returns.
+func (s *Server) SelfTest(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Errorf("lpoll: self-test: %w", err)
+	}
+	clientId := "lpoll-selftest-" + hex.EncodeToString(b[:])
+	sentinel := Event{Message: "selftest " + clientId, Time: time.Now(), Source: "selftest", PublishedAt: time.Now()}
+
+	now := time.Now()
+	client := &ClientState{Channel: make(chan Event, 1), LastSeen: now, RegisteredAt: now}
+	s.mu.Lock()
+	if _, ok := s.clientChannels[clientId]; ok {
+		s.mu.Unlock()
+		return fmt.Errorf("lpoll: self-test client %s already registered", clientId)
+	}
+	s.clientChannels[clientId] = client
+	s.mu.Unlock()
+	defer s.removeSelfTestClient(clientId, client)
+
+	// Send under the read lock so an eviction cannot close the channel
+	// mid-send; the channel is empty, so the send never blocks.
+	s.mu.RLock()
+	if s.clientChannels[clientId] != client {
+		s.mu.RUnlock()
+		return fmt.Errorf("lpoll: self-test publish: %w", ErrClientEvicted)
+	}
+	client.Channel <- sentinel
+	s.mu.RUnlock()
+
+	select {
+	case event, ok := <-s.clientChannel(client):
+		if !ok {
+			return fmt.Errorf("lpoll: self-test poll: %w", ErrClientEvicted)
+		}
+		if event.Message != sentinel.Message {
+			return fmt.Errorf("lpoll: self-test received %q, want %q", event.Message, sentinel.Message)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("lpoll: self-test poll: %w", ctx.Err())
+	}
+}
+
+func (s *Server) removeSelfTestClient(clientId string, client *ClientState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A drain, restore or cleanup may have evicted the client already.
+	if s.clientChannels[clientId] != client {
+		return
+	}
+	delete(s.clientChannels, clientId)
+	closeChannel(client)
+}
@@ -0,0 +1,38 @@
+package lpoll
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSelfTestBypassesPublishPath(t *testing.T) {
+	var published int
+	s := New(LpollOptions{
+		OnPublishSuccess: func(clientId string, event Event, queueDepth int) { published++ },
+	})
+	st := &countingStateStore{}
+	if err := s.SetStateStore(st); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := s.SubscribeChanges(ctx)
+
+	if err := s.SelfTest(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if published != 0 || s.stats.eventsPublished.Value() != 0 {
+		t.Fatal("self-test event went through the publish path")
+	}
+	if st.saves != 0 || st.deletes != 0 {
+		t.Fatalf("self-test client reached the state store: %d saves, %d deletes", st.saves, st.deletes)
+	}
+	select {
+	case change := <-changes:
+		t.Fatalf("self-test client announced: %+v", change)
+	default:
+	}
+	if n := len(s.clientChannels); n != 0 {
+		t.Fatalf("%d self-test clients left", n)
+	}
+}
@@ -0,0 +1,78 @@
+package lpoll
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// ClientMeta is the part of a client's state that outlives the process.
+type ClientMeta struct {
+	LastSeen     time.Time `json:"lastSeen"`
+	RegisteredAt time.Time `json:"registeredAt"`
+	// DeliveredSeq is the Seq of the last stored event delivered to the
+	// client, see SetEventStore. Zero means none.
+	DeliveredSeq uint64 `json:"deliveredSeq,omitempty"`
+}
+
+// StateStore persists client registrations so they survive a restart.
+type StateStore interface {
+	Save(clientId string, meta ClientMeta) error
+	Load(clientId string) (ClientMeta, bool, error)
+	Delete(clientId string) error
+	// LoadAll returns every persisted client keyed by its ID.
+	LoadAll() (map[string]ClientMeta, error)
+}
+
+// SetStateStore installs store and pre-registers every client it holds.
+// Restored clients have no channel until their first poll. It should be
+// called once, before the handlers start serving.
+func (s *Server) SetStateStore(store StateStore) error {
+	metas, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stateStore = store
+	for clientId, meta := range metas {
+		if _, ok := s.clientChannels[clientId]; ok {
+			continue
+		}
+		s.clientChannels[clientId] = &ClientState{
+			LastSeen:     meta.LastSeen,
+			RegisteredAt: meta.RegisteredAt,
+			deliveredSeq: meta.DeliveredSeq,
+		}
+	}
+	log.Printf("Restored %d clients from state store", len(metas))
+	return nil
+}
+
+// clientMeta returns the persisted part of client. Call it under the lock.
+func (s *Server) clientMeta(client *ClientState) ClientMeta {
+	return ClientMeta{
+		LastSeen:     client.LastSeen,
+		RegisteredAt: client.RegisteredAt,
+		DeliveredSeq: atomic.LoadUint64(&client.deliveredSeq),
+	}
+}
+
+func (s *Server) saveClientState(clientId string, meta ClientMeta) {
+	if s.stateStore == nil {
+		return
+	}
+	if err := s.stateStore.Save(clientId, meta); err != nil {
+		log.Printf("Failed to persist client %s: %v", clientId, err)
+	}
+}
+
+func (s *Server) deleteClientState(clientId string) {
+	if s.stateStore == nil {
+		return
+	}
+	if err := s.stateStore.Delete(clientId); err != nil {
+		log.Printf("Failed to delete persisted client %s: %v", clientId, err)
+	}
+}
@@ -0,0 +1,80 @@
+package lpoll
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingStateStore counts writes. It calls back into server, if set, to
+// catch store I/O done under the client lock.
+type countingStateStore struct {
+	mu      sync.Mutex
+	saves   int
+	deletes int
+	server  *Server
+}
+
+func (c *countingStateStore) Save(clientId string, meta ClientMeta) error {
+	if c.server != nil {
+		c.server.ClientExists(clientId)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saves++
+	return nil
+}
+
+func (c *countingStateStore) Load(clientId string) (ClientMeta, bool, error) {
+	return ClientMeta{}, false, nil
+}
+
+func (c *countingStateStore) Delete(clientId string) error {
+	if c.server != nil {
+		c.server.ClientExists(clientId)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deletes++
+	return nil
+}
+
+func (c *countingStateStore) LoadAll() (map[string]ClientMeta, error) {
+	return nil, nil
+}
+
+func TestConnectClientPersistsOnRegistration(t *testing.T) {
+	s := New(LpollOptions{})
+	st := &countingStateStore{}
+	if err := s.SetStateStore(st); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		s.EnsureClient("a")
+	}
+	if st.saves != 1 {
+		t.Fatalf("%d saves for one registration and four reconnects, want 1", st.saves)
+	}
+}
+
+func TestCleanupNowDeletesOutsideLock(t *testing.T) {
+	s := New(LpollOptions{})
+	st := &countingStateStore{server: s}
+	if err := s.SetStateStore(st); err != nil {
+		t.Fatal(err)
+	}
+	s.EnsureClient("a")
+	s.SetGlobalClientTimeout(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	done := make(chan int, 1)
+	go func() { done <- s.CleanupNow() }()
+	select {
+	case n := <-done:
+		if n != 1 || st.deletes != 1 {
+			t.Fatalf("evicted %d, deleted %d, want 1 and 1", n, st.deletes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("CleanupNow deadlocked calling the state store under the lock")
+	}
+}
@@ -0,0 +1,84 @@
+// Package store provides StateStore implementations for lpoll.
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/syosifov/lpoll/lpoll"
+	bolt "go.etcd.io/bbolt"
+)
+
+var clientsBucket = []byte("clients")
+
+// BoltStore is a lpoll.StateStore backed by a BoltDB file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+var _ lpoll.StateStore = (*BoltStore)(nil)
+
+// OpenBolt opens (or creates) the BoltDB file at path.
+func OpenBolt(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(clientsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) Save(clientId string, meta lpoll.ClientMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(clientsBucket).Put([]byte(clientId), data)
+	})
+}
+
+func (s *BoltStore) Load(clientId string) (lpoll.ClientMeta, bool, error) {
+	var meta lpoll.ClientMeta
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(clientsBucket).Get([]byte(clientId))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &meta)
+	})
+	return meta, found, err
+}
+
+func (s *BoltStore) Delete(clientId string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(clientsBucket).Delete([]byte(clientId))
+	})
+}
+
+func (s *BoltStore) LoadAll() (map[string]lpoll.ClientMeta, error) {
+	metas := make(map[string]lpoll.ClientMeta)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(clientsBucket).ForEach(func(k, v []byte) error {
+			var meta lpoll.ClientMeta
+			if err := json.Unmarshal(v, &meta); err != nil {
+				return err
+			}
+			metas[string(k)] = meta
+			return nil
+		})
+	})
+	return metas, err
+}
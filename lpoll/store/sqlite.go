@@ -0,0 +1,108 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/syosifov/lpoll/lpoll"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteEventStore is a lpoll.EventStore backed by a SQLite database.
+type SQLiteEventStore struct {
+	db *sql.DB
+}
+
+var (
+	_ lpoll.EventStore        = (*SQLiteEventStore)(nil)
+	_ lpoll.LimitedEventStore = (*SQLiteEventStore)(nil)
+)
+
+// OpenSQLite opens the SQLite database at dsn and creates the events table
+// if needed. The store uses a single connection in WAL mode, so concurrent
+// appends queue up instead of failing with SQLITE_BUSY.
+func OpenSQLite(dsn string) (*SQLiteEventStore, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time; more pooled connections only
+	// turn contention into "database is locked" errors.
+	db.SetMaxOpenConns(1)
+	for _, pragma := range []string{
+		`PRAGMA busy_timeout = 5000`,
+		`PRAGMA journal_mode = WAL`,
+	} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS events (
+		client_id TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		event BLOB NOT NULL,
+		PRIMARY KEY (client_id, seq)
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteEventStore{db: db}, nil
+}
+
+func (s *SQLiteEventStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteEventStore) Append(clientId string, e lpoll.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO events (client_id, seq, event) VALUES (?, ?, ?)`,
+		clientId, int64(e.Seq), data)
+	return err
+}
+
+func (s *SQLiteEventStore) Load(clientId string, afterSeq uint64) ([]lpoll.Event, error) {
+	rows, err := s.db.Query(`SELECT event FROM events WHERE client_id = ? AND seq > ? ORDER BY seq`,
+		clientId, int64(afterSeq))
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func (s *SQLiteEventStore) LoadLimit(clientId string, afterSeq uint64, limit int) ([]lpoll.Event, error) {
+	rows, err := s.db.Query(`SELECT event FROM events WHERE client_id = ? AND seq > ? ORDER BY seq LIMIT ?`,
+		clientId, int64(afterSeq), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]lpoll.Event, error) {
+	defer rows.Close()
+
+	var events []lpoll.Event
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var e lpoll.Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *SQLiteEventStore) Trim(clientId string, beforeSeq uint64) error {
+	_, err := s.db.Exec(`DELETE FROM events WHERE client_id = ? AND seq < ?`,
+		clientId, int64(beforeSeq))
+	return err
+}
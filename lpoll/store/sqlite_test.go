@@ -0,0 +1,51 @@
+package store
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/syosifov/lpoll/lpoll"
+)
+
+func TestSQLiteConcurrentAppend(t *testing.T) {
+	st, err := OpenSQLite(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	const writers, perWriter = 16, 50
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				e := lpoll.Event{Message: "m", Seq: uint64(w*perWriter + i + 1)}
+				if err := st.Append("c", e); err != nil {
+					mu.Lock()
+					failed++
+					mu.Unlock()
+					t.Log(err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if failed > 0 {
+		t.Fatalf("%d of %d appends failed", failed, writers*perWriter)
+	}
+
+	events, err := st.Load("c", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != writers*perWriter {
+		t.Fatalf("loaded %d events, want %d", len(events), writers*perWriter)
+	}
+}
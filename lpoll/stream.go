@@ -0,0 +1,39 @@
+package lpoll
+
+import "context"
+
+// AnnotatedEvent is an event together with the client it was sent to.
+type AnnotatedEvent struct {
+	ClientID string `json:"clientId"`
+	Event    Event  `json:"event"`
+}
+
+// streamBuffer is the capacity of each StreamAllEvents channel.
+const streamBuffer = 256
+
+// StreamAllEvents returns a channel receiving every event successfully sent
+// to any client until ctx is cancelled, when the channel is closed. Events
+// are dropped while the channel is full, so a slow consumer never blocks
+// publishing.
+func (s *Server) StreamAllEvents(ctx context.Context) <-chan AnnotatedEvent {
+	ch := make(chan AnnotatedEvent, streamBuffer)
+	remove := s.addTap(&tap{
+		match: func(string) bool { return true },
+		fn: func(clientId string, event Event) {
+			select {
+			case ch <- AnnotatedEvent{ClientID: clientId, Event: event}:
+			default:
+			}
+		},
+		inline: true,
+	})
+
+	go func() {
+		<-ctx.Done()
+		// Inline taps run under the taps read lock, so no send is in
+		// flight once remove returns.
+		remove()
+		close(ch)
+	}()
+	return ch
+}
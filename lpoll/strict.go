@@ -0,0 +1,40 @@
+package lpoll
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StrictMode turns LpollOptions.StrictMode on or off.
+func (s *Server) StrictMode(enabled bool) {
+	s.optsMu.Lock()
+	s.opts.StrictMode = enabled
+	s.optsMu.Unlock()
+}
+
+// ValidateEvent returns what StrictMode finds wrong with e, or nothing.
+func (s *Server) ValidateEvent(e Event) []string {
+	var problems []string
+	if e.Message == "" {
+		problems = append(problems, "message is empty")
+	}
+	if e.Type == "" {
+		problems = append(problems, "type is empty")
+	}
+	if e.Source == "" {
+		problems = append(problems, "source is empty")
+	}
+	return problems
+}
+
+// checkStrict rejects events ValidateEvent finds problems with when
+// StrictMode is on.
+func (s *Server) checkStrict(e Event) error {
+	if !s.options().StrictMode {
+		return nil
+	}
+	if problems := s.ValidateEvent(e); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrEventRejected, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package lpoll
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServerSummary is a short overview of a server's state.
+type ServerSummary struct {
+	Clients       int
+	QueueDepth    int
+	QueueCapacity int
+	Uptime        time.Duration
+}
+
+// String formats the summary on one line, e.g.
+// "lpoll | clients: 42 | depth: 120/420 | uptime: 3h14m5s".
+func (sum ServerSummary) String() string {
+	return fmt.Sprintf("lpoll | clients: %d | depth: %d/%d | uptime: %s",
+		sum.Clients, sum.QueueDepth, sum.QueueCapacity, sum.Uptime.Round(time.Second))
+}
+
+func (sum ServerSummary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Clients       int    `json:"clients"`
+		QueueDepth    int    `json:"queueDepth"`
+		QueueCapacity int    `json:"queueCapacity"`
+		Uptime        string `json:"uptime"`
+	}{sum.Clients, sum.QueueDepth, sum.QueueCapacity, sum.Uptime.Round(time.Second).String()})
+}
+
+// Summarize returns the current summary of s.
+func (s *Server) Summarize() ServerSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sum := ServerSummary{
+		Clients: len(s.clientChannels),
+		Uptime:  time.Since(s.startedAt),
+	}
+	for _, client := range s.clientChannels {
+		sum.QueueDepth += len(client.Channel)
+		sum.QueueCapacity += cap(client.Channel)
+	}
+	return sum
+}
+
+// HealthHandler serves the summary as JSON or plain text, following the
+// request's Accept header.
+func HealthHandler(c *gin.Context) {
+	sum := serverFrom(c).Summarize()
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEPlain) == gin.MIMEPlain {
+		c.String(http.StatusOK, sum.String())
+		return
+	}
+	c.JSON(http.StatusOK, sum)
+}
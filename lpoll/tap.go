@@ -0,0 +1,57 @@
+package lpoll
+
+import "path"
+
+type tap struct {
+	match func(clientId string) bool
+	fn    func(clientId string, event Event)
+	// inline taps are called on the publishing goroutine, in order.
+	inline bool
+}
+
+// Subscribe registers fn to be called for every event successfully sent to a
+// client whose ID matches pattern (path.Match glob syntax). Each call runs in
+// its own goroutine, but fn must still not block, as events keep arriving.
+// The returned function removes the tap and is safe to call more than once.
+func (s *Server) Subscribe(pattern string, fn func(clientId string, event Event)) func() {
+	return s.addTap(&tap{
+		match: func(clientId string) bool {
+			ok, _ := path.Match(pattern, clientId)
+			return ok
+		},
+		fn: fn,
+	})
+}
+
+func (s *Server) addTap(t *tap) (remove func()) {
+	s.tapsMu.Lock()
+	s.taps = append(s.taps, t)
+	s.tapsMu.Unlock()
+
+	return func() {
+		s.tapsMu.Lock()
+		defer s.tapsMu.Unlock()
+		for i, other := range s.taps {
+			if other == t {
+				s.taps = append(s.taps[:i:i], s.taps[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notifyTaps calls every tap matching clientId.
+func (s *Server) notifyTaps(clientId string, event Event) {
+	s.tapsMu.RLock()
+	defer s.tapsMu.RUnlock()
+	for _, t := range s.taps {
+		if !t.match(clientId) {
+			continue
+		}
+		if t.inline {
+			t.fn(clientId, event)
+		} else {
+			go t.fn(clientId, event)
+		}
+	}
+}
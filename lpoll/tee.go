@@ -0,0 +1,77 @@
+package lpoll
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// teeBuffer is how many events a Tee holds while dst is slow; further
+// events are not written.
+const teeBuffer = 64
+
+// Tee writes a copy of every event sent to clientId to dst, in order, as
+// "json" (one object per line), "text" (one line per event) or "msgpack".
+// Delivery to the client is not affected, and events are skipped rather than
+// delayed when dst cannot keep up. cancel removes the tap and is safe to call
+// more than once.
+func (s *Server) Tee(clientId string, dst io.Writer, format string) (cancel func()) {
+	write, err := teeWriter(dst, format)
+	if err != nil {
+		log.Printf("Not teeing client %s: %v", clientId, err)
+		return func() {}
+	}
+
+	events := make(chan Event, teeBuffer)
+	remove := s.addTap(&tap{
+		match:  func(id string) bool { return id == clientId },
+		inline: true,
+		fn: func(_ string, event Event) {
+			select {
+			case events <- event:
+			default:
+			}
+		},
+	})
+
+	go func() {
+		for event := range events {
+			if err := write(event); err != nil {
+				log.Printf("Tee write failed for client %s: %v", clientId, err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// remove waits for in-flight taps, so nothing sends on events
+			// once it returns.
+			remove()
+			close(events)
+		})
+	}
+}
+
+func teeWriter(dst io.Writer, format string) (func(Event) error, error) {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(dst)
+		return func(event Event) error { return enc.Encode(event) }, nil
+	case "text":
+		return func(event Event) error {
+			_, err := fmt.Fprintf(dst, "%s %s\n", event.Time.Format(time.RFC3339Nano), event.Message)
+			return err
+		}, nil
+	case "msgpack":
+		enc := msgpack.NewEncoder(dst)
+		return func(event Event) error { return enc.Encode(event) }, nil
+	default:
+		return nil, fmt.Errorf("unknown tee format %q", format)
+	}
+}
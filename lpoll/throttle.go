@@ -0,0 +1,59 @@
+package lpoll
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Throttle limits deliveries to clientId to rps events per second; events
+// wait in the client's buffer until they may be delivered. rps 0 removes
+// the throttle.
+func (s *Server) Throttle(clientId string, rps float64) error {
+	if rps < 0 {
+		return errors.New("lpoll: rps must not be negative")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clientChannels[clientId]
+	if !ok {
+		return ErrClientNotFound
+	}
+	if rps == 0 {
+		client.limiter = nil
+		return nil
+	}
+	client.limiter = rate.NewLimiter(rate.Limit(rps), 1)
+	return nil
+}
+
+// waitForToken waits until limiter has a token, without taking it. If the
+// token is not due before ctx's deadline it waits for ctx instead, so the
+// poll still ends at its timeout. It reports whether a token is available.
+func waitForToken(ctx context.Context, limiter *rate.Limiter) bool {
+	var delay time.Duration
+	if missing := 1 - limiter.Tokens(); missing > 0 {
+		delay = time.Duration(missing / float64(limiter.Limit()) * float64(time.Second))
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		<-ctx.Done()
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Server) clientLimiter(client *ClientState) *rate.Limiter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return client.limiter
+}
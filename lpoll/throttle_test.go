@@ -0,0 +1,53 @@
+package lpoll
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestThrottledPollWaitsForTimeout(t *testing.T) {
+	s := New(LpollOptions{})
+	s.SetGlobalTimeout(100 * time.Millisecond)
+	s.EnsureClient("c1")
+	if err := s.Throttle("c1", 0.1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.send("c1", Event{Message: "e1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pollMessage(t, s, "/poll/c1"); got != "e1" {
+		t.Fatalf("got %q, want \"e1\"", got)
+	}
+	// The next token is due in 10s, long after the poll timeout.
+	if err := s.send("c1", Event{Message: "e2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	w := serve(s, "GET", "/poll/c1", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("got %d, want 204", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("throttled poll returned after %v, before the poll timeout", elapsed)
+	}
+}
+
+func TestThrottledPollWithoutEventKeepsToken(t *testing.T) {
+	s := New(LpollOptions{})
+	s.SetGlobalTimeout(100 * time.Millisecond)
+	s.EnsureClient("c1")
+	if err := s.Throttle("c1", 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := pollMessage(t, s, "/poll/c1"); got != "" {
+		t.Fatalf("got %q, want a timeout", got)
+	}
+	if err := s.send("c1", Event{Message: "e1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pollMessage(t, s, "/poll/c1"); got != "e1" {
+		t.Fatalf("got %q, want \"e1\"", got)
+	}
+}
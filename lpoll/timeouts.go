@@ -0,0 +1,25 @@
+package lpoll
+
+import "time"
+
+// SetGlobalTimeout sets how long a poll waits for an event. Polls already
+// waiting keep the timeout they started with.
+func (s *Server) SetGlobalTimeout(d time.Duration) {
+	s.timeoutsMu.Lock()
+	s.pollTimeout = d
+	s.timeoutsMu.Unlock()
+}
+
+// SetGlobalClientTimeout sets how long a client may stay inactive before
+// the cleanup evicts it.
+func (s *Server) SetGlobalClientTimeout(d time.Duration) {
+	s.timeoutsMu.Lock()
+	s.clientTimeout = d
+	s.timeoutsMu.Unlock()
+}
+
+func (s *Server) timeouts() (poll, client time.Duration) {
+	s.timeoutsMu.RLock()
+	defer s.timeoutsMu.RUnlock()
+	return s.pollTimeout, s.clientTimeout
+}
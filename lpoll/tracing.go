@@ -0,0 +1,53 @@
+package lpoll
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/syosifov/lpoll"
+
+// withTraceParent copies the trace and span ID of the traceparent header in
+// h, if any, into event.
+func withTraceParent(h http.Header, event Event) Event {
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(h))
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return event
+	}
+	event.TraceID = sc.TraceID().String()
+	event.SpanID = sc.SpanID().String()
+	return event
+}
+
+// traceDelivery records a span for the delivery of event, linked to the
+// span that published it. It is a no-op for events without a trace.
+func traceDelivery(ctx context.Context, clientId string, event Event) {
+	if event.TraceID == "" {
+		return
+	}
+	traceID, err := trace.TraceIDFromHex(event.TraceID)
+	if err != nil {
+		return
+	}
+	spanID, err := trace.SpanIDFromHex(event.SpanID)
+	if err != nil {
+		return
+	}
+	link := trace.Link{SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})}
+	_, span := otel.Tracer(tracerName).Start(ctx, "lpoll.deliver",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithLinks(link),
+		trace.WithAttributes(attribute.String("lpoll.client_id", clientId)))
+	span.End()
+}
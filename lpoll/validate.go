@@ -0,0 +1,36 @@
+package lpoll
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var (
+	uuidPattern         = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	alphanumericPattern = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+)
+
+// UUIDValidator accepts client IDs in the canonical 8-4-4-4-12 UUID form.
+func UUIDValidator() func(string) error {
+	return func(clientId string) error {
+		if !uuidPattern.MatchString(clientId) {
+			return errors.New("clientId must be a UUID")
+		}
+		return nil
+	}
+}
+
+// AlphanumericValidator accepts non-empty client IDs of ASCII letters and
+// digits that are at most maxLen characters long.
+func AlphanumericValidator(maxLen int) func(string) error {
+	return func(clientId string) error {
+		if len(clientId) > maxLen {
+			return fmt.Errorf("clientId must be at most %d characters", maxLen)
+		}
+		if !alphanumericPattern.MatchString(clientId) {
+			return errors.New("clientId must be alphanumeric")
+		}
+		return nil
+	}
+}
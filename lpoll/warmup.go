@@ -0,0 +1,49 @@
+package lpoll
+
+import (
+	"log"
+	"time"
+)
+
+// WarmUp registers every client in clientIds that is not registered yet,
+// taking the client lock once for the whole batch. Clients restored from
+// the state store get their channel. MaxClients does not apply.
+func (s *Server) WarmUp(clientIds []string) {
+	type warmed struct {
+		id   string
+		meta ClientMeta
+	}
+	var created []warmed
+
+	now := time.Now()
+	s.mu.Lock()
+	for _, clientId := range clientIds {
+		client, ok := s.clientChannels[clientId]
+		switch {
+		case !ok:
+			client = &ClientState{
+				Channel:      make(chan Event, 1),
+				LastSeen:     now,
+				RegisteredAt: now,
+				deliveredSeq: lastSeq.Load(),
+			}
+			s.resetStoreCursor(client)
+			s.clientChannels[clientId] = client
+			s.emitChange(ClientRegistered, clientId)
+		case client.Channel == nil:
+			client.Channel = make(chan Event, 1)
+			client.LastSeen = now
+			s.resetStoreCursor(client)
+		default:
+			continue
+		}
+		created = append(created, warmed{clientId, s.clientMeta(client)})
+	}
+	s.mu.Unlock()
+
+	for _, w := range created {
+		s.saveClientState(w.id, w.meta)
+		s.loadStoredEvents(w.id, "")
+	}
+	log.Printf("Warmed up %d clients", len(created))
+}
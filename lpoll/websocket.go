@@ -0,0 +1,118 @@
+package lpoll
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+// ServeWebSocket upgrades the request and serves clientId over a single
+// WebSocket connection. The client's events are written as JSON frames and
+// every incoming {"message": ...} frame is published to the client's channel.
+// An alias is served as its canonical client.
+func (s *Server) ServeWebSocket(w http.ResponseWriter, r *http.Request, clientId string) {
+	if clientId == "" {
+		http.Error(w, "clientId is required", http.StatusBadRequest)
+		return
+	}
+	clientId = s.resolveAlias(clientId)
+
+	if err := s.checkNewClientID(clientId); err != nil {
+		http.Error(w, err.Error(), statusForError(err))
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed for client %s: %v", clientId, err)
+		return
+	}
+	defer conn.CloseNow()
+
+	client, firstConnect, err := s.connectClient(clientId, true)
+	if err != nil {
+		conn.Close(websocket.StatusTryAgainLater, err.Error())
+		return
+	}
+	if firstConnect {
+		s.loadStoredEvents(clientId, r.URL.Query().Get("after"))
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go func() {
+		defer cancel()
+		for {
+			var req struct {
+				Message string `json:"message"`
+				Schema  string `json:"schema"`
+				Type    string `json:"type"`
+				Source  string `json:"source"`
+			}
+			if err := wsjson.Read(ctx, conn, &req); err != nil {
+				return
+			}
+
+			event := Event{Message: req.Message, Time: time.Now(), Schema: req.Schema, Type: req.Type, Source: req.Source}
+			if err := s.checkStrict(event); err != nil {
+				log.Printf("Rejected WebSocket event for client %s: %v", clientId, err)
+				continue
+			}
+			if err := s.validateSchema(event); err != nil {
+				log.Printf("Rejected WebSocket event for client %s: %v", clientId, err)
+				continue
+			}
+			if err := s.publish(ctx, clientId, event); err != nil {
+				log.Printf("Failed to publish WebSocket event for client %s: %v", clientId, err)
+			}
+		}
+	}()
+
+	// Keep the client from being cleaned up while the connection is open.
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		s.loadStoredEvents(clientId, "")
+		ch, swapped := s.watchChannel(client)
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				conn.Close(websocket.StatusGoingAway, "client evicted")
+				return
+			}
+			s.received(client)
+			event = decompressEvent(event)
+			if err := wsjson.Write(ctx, conn, event); err != nil {
+				return
+			}
+			markDelivered(client, event)
+			s.notifyDelivered(ctx, clientId, event)
+		case <-swapped:
+			// ResizeClientChannel replaced the channel; wait on the new one.
+		case <-keepAlive.C:
+			s.mu.Lock()
+			client.LastSeen = time.Now()
+			s.mu.Unlock()
+		case <-ctx.Done():
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+	}
+}
+
+func WebSocketHandler(c *gin.Context) {
+	s := serverFrom(c)
+	clientId, err := extractClientID(c, s.options())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	s.ServeWebSocket(c.Writer, c.Request, clientId)
+}
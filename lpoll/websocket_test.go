@@ -0,0 +1,54 @@
+package lpoll
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+func TestWebSocketAlias(t *testing.T) {
+	s := New(LpollOptions{})
+	s.EnsureClient("canonical")
+	if err := s.AddClientAlias("alias", "canonical"); err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	s.RegisterRoutes(engine)
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/alias", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	resp, err := http.Post(srv.URL+"/publish/alias", "application/json", strings.NewReader(`{"message":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("publish: got %d", resp.StatusCode)
+	}
+
+	var event Event
+	if err := wsjson.Read(ctx, conn, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Message != "hi" {
+		t.Fatalf("got %q", event.Message)
+	}
+	if s.ClientExists("alias") {
+		t.Fatal("the alias was registered as a client")
+	}
+}